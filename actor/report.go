@@ -0,0 +1,44 @@
+package actor
+
+import (
+	"encoding/csv"
+	"os"
+	"strconv"
+
+	"github.com/ontio/ontology-stress-test/common/report"
+)
+
+// txnReport is the machine readable result of the received transactions, it
+// share the schema of the ont-bench report so ont-bench compare read it
+type txnReport struct {
+	report.Header
+	Summary *report.Summary         `json:"Summary"`
+	Series  []*report.SecondSummary `json:"Series,omitempty"`
+}
+
+func newTxnReport(tool, version string) *txnReport {
+	return &txnReport{
+		Header:  report.NewHeader(tool, version),
+		Summary: &report.Summary{},
+	}
+}
+
+func (self *txnReport) writeJSON(file string) error {
+	return report.WriteJSON(file, self)
+}
+
+// writeCSV write the received transactions of every second
+func (self *txnReport) writeCSV(file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write([]string{"second", "count"})
+	for _, s := range self.Series {
+		w.Write([]string{strconv.Itoa(s.Second), strconv.FormatUint(s.Count, 10)})
+	}
+	w.Flush()
+	return w.Error()
+}
@@ -3,10 +3,13 @@ package actor
 import (
 	"fmt"
 	"reflect"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/ontio/ontology-eventbus/actor"
+	"github.com/ontio/ontology-stress-test/common/metrics"
+	"github.com/ontio/ontology-stress-test/common/report"
 	"github.com/ontio/ontology/common/log"
 	"github.com/ontio/ontology/core/types"
 	tc "github.com/ontio/ontology/txnpool/common"
@@ -15,6 +18,10 @@ import (
 var TxCnt uint64
 var TxCntLatest uint64
 
+var txnStart = time.Now()
+var txnSeriesLock sync.Mutex
+var txnSeries []uint64 //received txn count of every second
+
 var DefTxnPid *actor.PID
 
 type TxnPoolActor struct {
@@ -56,12 +63,50 @@ func AddTransaction(transaction *types.Transaction) {
 	atomic.AddUint64(&(TxCnt), 1)
 }
 
+// EnableMetrics register the metrics of the received transactions
+func EnableMetrics() {
+	metrics.NewCounterFunc("netstress_received_txn_total", "Transactions received by the txn pool",
+		func() float64 { return float64(atomic.LoadUint64(&TxCnt)) })
+}
+
 func PrintTxnInfo() {
 	txnPerSnd := TxCnt - TxCntLatest
 	TxCntLatest = TxCnt
+	txnSeriesLock.Lock()
+	txnSeries = append(txnSeries, txnPerSnd)
+	txnSeriesLock.Unlock()
 	fmt.Printf("total txn count %d,TPS = %d/s\n", TxCnt, txnPerSnd)
 }
 
+// WriteTxnReport write the json report and the csv series of the received
+// transactions, an empty file name skip the output
+func WriteTxnReport(reportFile, csvFile, version string) error {
+	result := newTxnReport("net-stress-test", version)
+	result.Start = txnStart
+	result.End = time.Now()
+	summary := result.Summary
+	summary.Success = atomic.LoadUint64(&TxCnt)
+	summary.Requests = summary.Success
+	summary.Seconds = result.End.Sub(result.Start).Seconds()
+	if summary.Seconds > 0 {
+		summary.TPS = float64(summary.Success) / summary.Seconds
+	}
+	txnSeriesLock.Lock()
+	for i, cnt := range txnSeries {
+		result.Series = append(result.Series, &report.SecondSummary{Second: i, Count: cnt})
+	}
+	txnSeriesLock.Unlock()
+	if reportFile != "" {
+		if err := result.writeJSON(reportFile); err != nil {
+			return err
+		}
+	}
+	if csvFile != "" {
+		return result.writeCSV(csvFile)
+	}
+	return nil
+}
+
 func LoopPrintActorInfo() {
 	ticker := time.NewTicker(time.Second)
 	for {
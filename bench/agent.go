@@ -0,0 +1,266 @@
+package bench
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// states of an agent
+const (
+	AGENT_IDLE    = "idle"  //waiting for the plan
+	AGENT_SETUP   = "setup" //funding senders and starting the confirmer
+	AGENT_READY   = "ready" //waiting at the start barrier
+	AGENT_RUNNING = "running"
+	AGENT_DONE    = "done"
+	AGENT_FAILED  = "failed"
+)
+
+// Plan is the share of a distributed run assigned to an agent
+type Plan struct {
+	Index  int      `json:"Index"`
+	Agents int      `json:"Agents"`
+	Args   []string `json:"Args"` //ont-bench flags of the agent's run
+}
+
+type AgentStatus struct {
+	State string `json:"State"`
+	Error string `json:"Error,omitempty"`
+}
+
+// AgentResult is the outcome of an agent's run, the histograms are kept
+// whole so the coordinator can merge the percentiles
+type AgentResult struct {
+	Index      int                   `json:"Index"`
+	Report     *Report               `json:"Report"`
+	Histograms map[string]*Histogram `json:"Histograms"`
+}
+
+// Agent run its share of a distributed bench under the control of the
+// coordinator over http
+type Agent struct {
+	lock      sync.Mutex
+	state     string
+	err       string
+	planCh    chan *Plan
+	startCh   chan struct{}
+	stopCh    chan struct{}
+	fetchedCh chan struct{}
+	result    *AgentResult
+	listener  net.Listener
+	server    *http.Server
+}
+
+func NewAgent() *Agent {
+	return &Agent{
+		state:     AGENT_IDLE,
+		planCh:    make(chan *Plan, 1),
+		startCh:   make(chan struct{}),
+		stopCh:    make(chan struct{}),
+		fetchedCh: make(chan struct{}),
+	}
+}
+
+// Serve start serving the coordinator at address, port 0 for any free one
+func (self *Agent) Serve(address string) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/plan", self.servePlan)
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		self.lock.Lock()
+		status := &AgentStatus{State: self.state, Error: self.err}
+		self.lock.Unlock()
+		json.NewEncoder(w).Encode(status)
+		if status.State == AGENT_FAILED {
+			self.fetched()
+		}
+	})
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		self.transit(w, AGENT_READY, AGENT_RUNNING, self.startCh)
+	})
+	mux.HandleFunc("/stop", func(w http.ResponseWriter, r *http.Request) {
+		self.lock.Lock()
+		defer self.lock.Unlock()
+		select {
+		case <-self.stopCh:
+		default:
+			close(self.stopCh)
+		}
+	})
+	mux.HandleFunc("/result", self.serveResult)
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("listen %s error:%s", address, err)
+	}
+	self.listener = listener
+	self.server = &http.Server{Handler: mux}
+	go func() {
+		err := self.server.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			fmt.Printf("agent server error:%s\n", err)
+		}
+	}()
+	return nil
+}
+
+// Address return the address the agent is serving at
+func (self *Agent) Address() string {
+	return self.listener.Addr().String()
+}
+
+// Close stop serving the coordinator
+func (self *Agent) Close() {
+	self.server.Close()
+}
+
+func (self *Agent) servePlan(w http.ResponseWriter, r *http.Request) {
+	plan := &Plan{}
+	err := json.NewDecoder(r.Body).Decode(plan)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid plan:%s", err), http.StatusBadRequest)
+		return
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.state != AGENT_IDLE {
+		http.Error(w, "agent is "+self.state, http.StatusConflict)
+		return
+	}
+	self.state = AGENT_SETUP
+	self.planCh <- plan
+}
+
+// transit move the agent from state from to state to and close ch
+func (self *Agent) transit(w http.ResponseWriter, from, to string, ch chan struct{}) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.state != from {
+		http.Error(w, "agent is "+self.state, http.StatusConflict)
+		return
+	}
+	self.state = to
+	close(ch)
+}
+
+func (self *Agent) serveResult(w http.ResponseWriter, r *http.Request) {
+	self.lock.Lock()
+	result, state := self.result, self.state
+	self.lock.Unlock()
+	if result == nil {
+		http.Error(w, "agent is "+state, http.StatusConflict)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+	self.fetched()
+}
+
+// fetched close fetchedCh once the coordinator got the outcome of the run
+func (self *Agent) fetched() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	select {
+	case <-self.fetchedCh:
+	default:
+		close(self.fetchedCh)
+	}
+}
+
+// Plan block until the coordinator assign the plan
+func (self *Agent) Plan() *Plan {
+	return <-self.planCh
+}
+
+// Ready mark the setup is done and return the channel closed when the
+// coordinator start the run
+func (self *Agent) Ready() <-chan struct{} {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.state = AGENT_READY
+	return self.startCh
+}
+
+// Stopped return the channel closed when the coordinator stop the run
+func (self *Agent) Stopped() <-chan struct{} {
+	return self.stopCh
+}
+
+// Finish keep the result of the run for the coordinator
+func (self *Agent) Finish(result *AgentResult) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.state = AGENT_DONE
+	self.result = result
+}
+
+// Fail mark the run failed with reason
+func (self *Agent) Fail(reason string) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.state = AGENT_FAILED
+	self.err = reason
+}
+
+// State return the state of the agent
+func (self *Agent) State() string {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.state
+}
+
+// Fetched return the channel closed when the coordinator fetched the result
+// of a finished run or the status of a failed one
+func (self *Agent) Fetched() <-chan struct{} {
+	return self.fetchedCh
+}
+
+// RunAgent wait for the plan of the coordinator and run it with run, which
+// pass the start barrier with Ready and hand the result to Finish. The agent
+// fail when run return a non zero exit code or never finished, and stay until
+// the coordinator fetched the outcome or timeout. It return the exit code of
+// run, 1 for a run which did not finish
+func RunAgent(agent *Agent, run func(plan *Plan) int, timeout time.Duration) int {
+	code := run(agent.Plan())
+	if code == 0 && agent.State() != AGENT_DONE {
+		code = 1
+	}
+	if code != 0 {
+		agent.Fail(fmt.Sprintf("agent run failed with exit code %d, see the output of the agent", code))
+	}
+	select {
+	case <-agent.Fetched():
+	case <-time.After(timeout):
+		fmt.Println("outcome not fetched by the coordinator")
+	}
+	return code
+}
+
+// Collect return the histograms of a run by name for AgentResult
+func Collect(stats *Stats, confirmer *Confirmer, endpoints *EndpointPool, signer *Signer) map[string]*Histogram {
+	hists := map[string]*Histogram{
+		"latency": stats.latency,
+		"service": stats.service,
+		"warmup":  stats.warm.Latency,
+	}
+	for _, kind := range stats.Kinds() {
+		hists["kind/"+kind] = stats.Kind(kind).Latency
+	}
+	for i, op := range stats.phases {
+		hists["phase/"+strconv.Itoa(i)] = op.Latency
+	}
+	for i, h := range stats.Series() {
+		hists["second/"+strconv.Itoa(i)] = h
+	}
+	for _, ep := range endpoints.endpoints {
+		hists["endpoint/"+ep.Address] = ep.latency
+	}
+	if confirmer != nil {
+		hists["confirm"] = confirmer.latency
+	}
+	if signer != nil {
+		hists["sign"] = signer.latency
+	}
+	return hists
+}
@@ -0,0 +1,275 @@
+package bench
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// startAgents serve n agents on free ports and return their addresses
+func startAgents(t *testing.T, n int) ([]*Agent, []string) {
+	agents := make([]*Agent, 0, n)
+	addresses := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		agent := NewAgent()
+		if err := agent.Serve("127.0.0.1:0"); err != nil {
+			t.Fatalf("Serve error:%s", err)
+		}
+		agents = append(agents, agent)
+		addresses = append(addresses, agent.Address())
+	}
+	return agents, addresses
+}
+
+// runPlan run the plan of an agent the way ont-bench does: wait at the start
+// barrier, submit through runner until the end or the stop of the
+// coordinator, and hand the result to the agent
+func runPlan(agent *Agent, plan *Plan, runner *Runner, passed *int32) int {
+	select {
+	case <-agent.Ready():
+	case <-agent.Stopped():
+		return 1
+	}
+	atomic.AddInt32(passed, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-agent.Stopped():
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	<-runner.Run(ctx)
+	runner.Stats.Finish()
+	report := NewReport("ont-bench", "test")
+	runner.Stats.Summarize(report)
+	runner.Endpoints.Summarize(report)
+	runner.Confirmer.Summarize(report)
+	agent.Finish(&AgentResult{
+		Index:      plan.Index,
+		Report:     report,
+		Histograms: Collect(runner.Stats, runner.Confirmer, runner.Endpoints, nil),
+	})
+	return 0
+}
+
+func assign(t *testing.T, coordinator *Coordinator, n int) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	plans := make([]*Plan, 0, n)
+	for i := 0; i < n; i++ {
+		plans = append(plans, &Plan{Index: i, Agents: n})
+	}
+	if err := coordinator.Assign(plans); err != nil {
+		t.Fatalf("Assign error:%s", err)
+	}
+	return ctx
+}
+
+func TestCoordinator(t *testing.T) {
+	const agents = 3
+	nodes, addresses := startAgents(t, agents)
+	passed := int32(0)
+	codes := make(chan int, agents)
+	for i, agent := range nodes {
+		defer agent.Close()
+		//agent i submit (i+1)*10 transfers, every 5th is rejected
+		sent := uint64(0)
+		runner := newRunner(t, (i+1)*10, 5, &sent)
+		go func(agent *Agent) {
+			codes <- RunAgent(agent, func(plan *Plan) int {
+				return runPlan(agent, plan, runner, &passed)
+			}, 5*time.Second)
+		}(agent)
+	}
+	coordinator := NewCoordinator(addresses)
+	ctx := assign(t, coordinator, agents)
+	if err := coordinator.Wait(ctx, AGENT_READY); err != nil {
+		t.Fatalf("Wait ready error:%s", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt32(&passed); n != 0 {
+		t.Fatalf("%d agents passed the start barrier before the start", n)
+	}
+	if err := coordinator.Start(); err != nil {
+		t.Fatalf("Start error:%s", err)
+	}
+	if err := coordinator.Wait(ctx, AGENT_DONE); err != nil {
+		t.Fatalf("Wait done error:%s", err)
+	}
+	if n := atomic.LoadInt32(&passed); n != agents {
+		t.Fatalf("%d agents passed the start barrier, want %d", n, agents)
+	}
+	select {
+	case <-codes:
+		t.Fatal("agent returned before its result was fetched")
+	case <-time.After(100 * time.Millisecond):
+	}
+	results, err := coordinator.Results()
+	if err != nil {
+		t.Fatalf("Results error:%s", err)
+	}
+	for i := 0; i < agents; i++ {
+		select {
+		case code := <-codes:
+			if code != 0 {
+				t.Fatalf("agent exit code %d", code)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("agent still waiting after its result was fetched")
+		}
+	}
+
+	report := NewReport("ont-bench", "test")
+	MergeResults(report, results)
+	//8+16+24 successes and 2+4+6 failures
+	if report.Summary.Success != 48 || report.Summary.Errors != 12 || report.Summary.Requests != 60 {
+		t.Fatalf("merged summary %+v", report.Summary)
+	}
+	if op := report.Kinds[OP_ONT_TRANSFER]; op == nil || op.Success != 48 || op.Error != 12 {
+		t.Fatalf("merged kind %+v", op)
+	}
+	if c := report.Confirm; c == nil || c.Tracked != 48 {
+		t.Fatalf("merged confirm %+v", c)
+	}
+	if report.Latency.Count != 48 {
+		t.Fatalf("merged latency count %d, want 48", report.Latency.Count)
+	}
+	success := uint64(0)
+	for _, ep := range report.Endpoints {
+		success += ep.Success
+	}
+	if len(report.Endpoints) != 3 || success != 48 {
+		t.Fatalf("merged endpoints %+v", report.Endpoints)
+	}
+}
+
+func TestAgentFailure(t *testing.T) {
+	nodes, addresses := startAgents(t, 2)
+	codes := make(chan int, 2)
+	for i, agent := range nodes {
+		defer agent.Close()
+		code := 0
+		if i == 1 {
+			code = 3
+		}
+		go func(agent *Agent) {
+			codes <- RunAgent(agent, func(plan *Plan) int {
+				if code != 0 {
+					return code
+				}
+				//a run which never finished
+				<-agent.Ready()
+				return 0
+			}, 5*time.Second)
+		}(agent)
+	}
+	coordinator := NewCoordinator(addresses)
+	ctx := assign(t, coordinator, 2)
+	err := coordinator.Wait(ctx, AGENT_READY)
+	if err == nil || !strings.Contains(err.Error(), "exit code 3") {
+		t.Fatalf("Wait error %v, want the exit code of the failed agent", err)
+	}
+	select {
+	case code := <-codes:
+		if code != 3 {
+			t.Fatalf("failed agent exit code %d, want 3", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("failed agent still waiting after its status was fetched")
+	}
+	if err = coordinator.Start(); err == nil {
+		t.Fatal("start of a failed agent accepted")
+	}
+	//the other agent is released but its run never finished
+	for {
+		err = coordinator.Wait(ctx, AGENT_DONE)
+		if err == nil || ctx.Err() != nil {
+			t.Fatalf("Wait error %v, want the failure of the unfinished agent", err)
+		}
+		if strings.Contains(err.Error(), "exit code 1") {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case code := <-codes:
+		if code != 1 {
+			t.Fatalf("unfinished agent exit code %d, want 1", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("unfinished agent still waiting after its status was fetched")
+	}
+}
+
+func TestAgentServe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen error:%s", err)
+	}
+	defer listener.Close()
+	if err = NewAgent().Serve(listener.Addr().String()); err == nil {
+		t.Fatal("Serve on a busy address succeeded")
+	}
+}
+
+func TestMergeBlocks(t *testing.T) {
+	start := time.Now()
+	row := func(height uint32, txs int, submitted, committed uint64) *BlockRow {
+		return &BlockRow{
+			Height:    height,
+			Time:      start.Add(time.Duration(height) * time.Second),
+			Txs:       txs,
+			Submitted: submitted,
+			Committed: committed,
+			Lag:       int64(submitted) - int64(committed),
+		}
+	}
+	//both agents see the blocks 1 to 3 of 0, 10 and 20 txs, 2 of the 10 are
+	//the warmup of the first agent, and the second one start at block 2
+	first := &BlockSummary{
+		GenBlockTime: 1,
+		Blocks:       3,
+		Submitted:    14,
+		Committed:    28,
+		SubmittedTPS: 7,
+		Series:       []*BlockRow{row(1, 0, 0, 0), row(2, 10, 10, 8), row(3, 20, 14, 28)},
+	}
+	second := &BlockSummary{
+		GenBlockTime: 1,
+		Blocks:       2,
+		Submitted:    14,
+		Committed:    30,
+		SubmittedTPS: 7,
+		Series:       []*BlockRow{row(2, 10, 6, 10), row(3, 20, 14, 30)},
+	}
+	sum := mergeBlocks([]*BlockSummary{second, first})
+	if sum.Submitted != 28 || sum.SubmittedTPS != 14 || sum.Committed != 28 {
+		t.Fatalf("merged blocks %+v", sum)
+	}
+	want := []struct {
+		submitted, committed uint64
+	}{{0, 0}, {16, 8}, {28, 28}}
+	if len(sum.Series) != len(want) {
+		t.Fatalf("merged series of %d rows, want %d", len(sum.Series), len(want))
+	}
+	for i, w := range want {
+		r := sum.Series[i]
+		if r.Submitted != w.submitted || r.Committed != w.committed || r.Lag != int64(w.submitted)-int64(w.committed) {
+			t.Fatalf("merged row %d %+v, want submitted %d committed %d", i, r, w.submitted, w.committed)
+		}
+	}
+	if sum.MaxLag != 8 || sum.FinalLag != 0 {
+		t.Fatalf("merged lag max %d final %d, want 8 and 0", sum.MaxLag, sum.FinalLag)
+	}
+	if sum.CommittedTPS != 14 {
+		t.Fatalf("merged committed tps %v, want 14", sum.CommittedTPS)
+	}
+	if first.Submitted != 14 || first.Series[1].Submitted != 10 {
+		t.Fatal("the summary of an agent changed by the merge")
+	}
+}
@@ -0,0 +1,214 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ontio/ontology/common"
+)
+
+// factor of GenBlockTime beyond which a block interval is late
+const BLOCK_LATE_FACTOR = 1.5
+
+// BlockRow is the committed and submitted counts when a block was seen
+type BlockRow struct {
+	Height    uint32    `json:"Height"`
+	Time      time.Time `json:"Time"`
+	Txs       int       `json:"Txs"`
+	Interval  float64   `json:"Interval"` //seconds since the previous block, 0 for the first one
+	TPS       float64   `json:"TPS"`      //transactions of the block over its interval
+	Submitted uint64    `json:"Submitted"`
+	Committed uint64    `json:"Committed"`
+	Lag       int64     `json:"Lag"` //submitted but not committed yet
+}
+
+// Analyzer follow the blocks during and after a run and compare the committed
+// throughput with the submitted one, to tell whether the txpool, the consensus
+// or the block size limit the run. Committed counts include every transaction
+// of the blocks, not only the ones of the bench, except the warmup ones
+type Analyzer struct {
+	lock         sync.Mutex
+	genBlockTime time.Duration
+	maxBlockTxs  int
+	submitted    uint64
+	firstSubmit  time.Time
+	lastSubmit   time.Time
+	committed    uint64
+	warm         map[common.Uint256]bool //warmup transactions not committed yet
+	lastCommit   time.Time
+	blocks       []*BlockRow
+	intervals    *Histogram
+}
+
+// NewAnalyzer return an Analyzer of a node producing a block every
+// genBlockTime with at most maxBlockTxs transactions, 0 if unknown
+func NewAnalyzer(genBlockTime time.Duration, maxBlockTxs int) *Analyzer {
+	return &Analyzer{
+		genBlockTime: genBlockTime,
+		maxBlockTxs:  maxBlockTxs,
+		warm:         make(map[common.Uint256]bool),
+		intervals:    NewHistogram(),
+	}
+}
+
+// Submit count a transaction accepted by the node at at
+func (self *Analyzer) Submit(at time.Time) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.submitted == 0 {
+		self.firstSubmit = at
+	}
+	self.submitted++
+	self.lastSubmit = at
+}
+
+// SubmitWarmup mark a warmup transaction, it is left out of the committed
+// counts
+func (self *Analyzer) SubmitWarmup(hash common.Uint256) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.warm[hash] = true
+}
+
+func (self *Analyzer) OnBlock(block *BlockInfo) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	row := &BlockRow{Height: block.Height, Time: block.Timestamp, Txs: len(block.Hashes)}
+	if n := len(self.blocks); n > 0 {
+		interval := block.Timestamp.Sub(self.blocks[n-1].Time)
+		if interval > 0 {
+			row.Interval = interval.Seconds()
+			row.TPS = float64(row.Txs) / row.Interval
+			self.intervals.Record(interval)
+		}
+	}
+	committed := uint64(0)
+	for _, hash := range block.Hashes {
+		if self.warm[hash] {
+			delete(self.warm, hash)
+			continue
+		}
+		committed++
+	}
+	self.committed += committed
+	if committed > 0 {
+		self.lastCommit = block.At
+	}
+	row.Submitted = self.submitted
+	row.Committed = self.committed
+	row.Lag = int64(row.Submitted) - int64(row.Committed)
+	self.blocks = append(self.blocks, row)
+}
+
+// Wait block until the committed count catch up with the submitted one, no
+// transaction is committed for idle, or ctx is done
+func (self *Analyzer) Wait(ctx context.Context, idle time.Duration) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	start := time.Now()
+	for {
+		self.lock.Lock()
+		done := self.committed >= self.submitted
+		last := self.lastCommit
+		self.lock.Unlock()
+		if last.Before(start) {
+			last = start
+		}
+		if done || time.Since(last) > idle {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Summarize fill the block summary of report
+func (self *Analyzer) Summarize(report *Report) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	sum := &BlockSummary{
+		GenBlockTime: self.genBlockTime.Seconds(),
+		Blocks:       len(self.blocks),
+		Submitted:    self.submitted,
+		Committed:    self.committed,
+		Interval:     Summarize(self.intervals),
+		Series:       make([]*BlockRow, len(self.blocks)),
+	}
+	copy(sum.Series, self.blocks)
+	txs := 0
+	for _, row := range self.blocks {
+		txs += row.Txs
+		if row.Txs == 0 {
+			sum.EmptyBlocks++
+		}
+		if self.maxBlockTxs > 0 && row.Txs >= self.maxBlockTxs {
+			sum.FullBlocks++
+		}
+		if row.Txs > sum.MaxTxs {
+			sum.MaxTxs = row.Txs
+		}
+		if row.Interval > self.genBlockTime.Seconds()*BLOCK_LATE_FACTOR {
+			sum.LateBlocks++
+		}
+		if row.Lag > sum.MaxLag {
+			sum.MaxLag = row.Lag
+		}
+	}
+	if n := len(self.blocks); n > 0 {
+		sum.MeanTxs = float64(txs) / float64(n)
+		sum.FinalLag = self.blocks[n-1].Lag
+	}
+	//the transactions of the first block were committed before its unknown
+	//interval, leave them out of the rate
+	if n := len(self.blocks); n > 1 {
+		seconds := self.blocks[n-1].Time.Sub(self.blocks[0].Time).Seconds()
+		if seconds > 0 {
+			sum.CommittedTPS = float64(sum.Committed-self.blocks[0].Committed) / seconds
+		}
+	}
+	if seconds := self.lastSubmit.Sub(self.firstSubmit).Seconds(); seconds > 0 {
+		sum.SubmittedTPS = float64(self.submitted) / seconds
+	}
+	sum.Limit = blockLimit(sum)
+	report.Blocks = sum
+}
+
+// blockLimit tell what limit the committed throughput of sum
+func blockLimit(sum *BlockSummary) string {
+	intervals := sum.Blocks - 1
+	nonEmpty := sum.Blocks - sum.EmptyBlocks
+	switch {
+	case intervals < 2:
+		return "too few blocks to tell"
+	case sum.LateBlocks*2 > intervals:
+		return "blocks are late against GenBlockTime, the consensus or the ledger commit is the limit"
+	case nonEmpty > 0 && sum.FullBlocks*2 >= nonEmpty:
+		return "blocks are full at MaxTransactionInBlock, the block size is the limit"
+	case float64(sum.MaxLag) > 2*sum.SubmittedTPS*sum.GenBlockTime:
+		return "blocks are on time but the committed count fall behind, the txpool is the limit"
+	default:
+		return "the committed throughput keep up with the submission"
+	}
+}
+
+func (self *Analyzer) Print() {
+	report := &Report{}
+	self.Summarize(report)
+	sum := report.Blocks
+	fmt.Printf("blocks:%d, committed:%d, submitted:%d, committed tps:%.1f, submitted tps:%.1f\n",
+		sum.Blocks, sum.Committed, sum.Submitted, sum.CommittedTPS, sum.SubmittedTPS)
+	fmt.Printf("block txs mean:%.1f, max:%d, empty:%d, full:%d, late:%d against %.0fs, lag max:%d, final:%d\n",
+		sum.MeanTxs, sum.MaxTxs, sum.EmptyBlocks, sum.FullBlocks, sum.LateBlocks, sum.GenBlockTime, sum.MaxLag, sum.FinalLag)
+	fmt.Printf("interval    %s\n", formatHistogram(self.intervals))
+	fmt.Printf("%10s %8s %10s %10s %10s %10s %10s\n", "height", "txs", "interval", "tps", "submitted", "committed", "lag")
+	for _, row := range sum.Series {
+		fmt.Printf("%10d %8d %10.1f %10.1f %10d %10d %10d\n", row.Height, row.Txs, row.Interval, row.TPS,
+			row.Submitted, row.Committed, row.Lag)
+	}
+	fmt.Println(sum.Limit)
+}
@@ -0,0 +1,203 @@
+package bench
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ontio/ontology/common"
+)
+
+type balanceKey struct {
+	address common.Address
+	asset   string
+}
+
+// Discrepancy is a balance which does not change as the successful
+// operations expect
+type Discrepancy struct {
+	Address  string `json:"Address"`
+	Asset    string `json:"Asset"`
+	Start    uint64 `json:"Start"`
+	End      uint64 `json:"End"`
+	Expected int64  `json:"Expected"` //expected delta
+	Actual   int64  `json:"Actual"`   //actual delta
+}
+
+type AuditSummary struct {
+	Passed        bool           `json:"Passed"`
+	Operations    uint64         `json:"Operations"` //successful operations changing balances
+	Excluded      uint64         `json:"Excluded"`   //operations never included in a block
+	Discrepancies []*Discrepancy `json:"Discrepancies"`
+}
+
+// Auditor check the balances of the senders and recipients change exactly as
+// the successful operations of the run expect, any discrepancy is a
+// correctness failure of the node under load
+type Auditor struct {
+	lock       sync.Mutex
+	start      map[balanceKey]uint64
+	expected   map[balanceKey]int64
+	effects    map[common.Uint256][]*Effect
+	operations uint64
+	excluded   uint64
+	result     *AuditSummary
+}
+
+func NewAuditor() *Auditor {
+	return &Auditor{
+		start:    make(map[balanceKey]uint64),
+		expected: make(map[balanceKey]int64),
+		effects:  make(map[common.Uint256][]*Effect),
+	}
+}
+
+func (self *Auditor) balances(client BalanceClient) (map[balanceKey]uint64, error) {
+	self.lock.Lock()
+	keys := make([]balanceKey, 0, len(self.start))
+	for key := range self.start {
+		keys = append(keys, key)
+	}
+	self.lock.Unlock()
+	addrs := make(map[common.Address]bool)
+	for _, key := range keys {
+		addrs[key.address] = true
+	}
+	return fetchBalances(client, addrs)
+}
+
+func fetchBalances(client BalanceClient, addrs map[common.Address]bool) (map[balanceKey]uint64, error) {
+	ret := make(map[balanceKey]uint64)
+	for addr := range addrs {
+		balance, err := client.GetBalance(addr)
+		if err != nil {
+			return nil, fmt.Errorf("GetBalance %s error:%s", addr.ToBase58(), err)
+		}
+		ret[balanceKey{addr, OP_ONT_TRANSFER}] = balance.Ont
+		ret[balanceKey{addr, OP_ONG_TRANSFER}] = balance.Ong
+	}
+	return ret, nil
+}
+
+// Snapshot record the start balances of addrs
+func (self *Auditor) Snapshot(client BalanceClient, addrs []common.Address) error {
+	set := make(map[common.Address]bool)
+	for _, addr := range addrs {
+		set[addr] = true
+	}
+	start, err := fetchBalances(client, set)
+	if err != nil {
+		return err
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.start = start
+	return nil
+}
+
+// Record add the effects of a successful operation with hash
+func (self *Auditor) Record(hash common.Uint256, effects []*Effect) {
+	if len(effects) == 0 {
+		return
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.operations++
+	self.effects[hash] = effects
+	for _, effect := range effects {
+		self.expected[balanceKey{effect.Address, effect.Asset}] += effect.Delta
+	}
+}
+
+// Expect add the effects of a setup transfer made after the snapshot, it is
+// not counted as an operation of the run
+func (self *Auditor) Expect(effects []*Effect) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	for _, effect := range effects {
+		self.expected[balanceKey{effect.Address, effect.Asset}] += effect.Delta
+	}
+}
+
+// Exclude drop the effects of the operations never included in a block
+func (self *Auditor) Exclude(hashes []common.Uint256) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	for _, hash := range hashes {
+		effects, ok := self.effects[hash]
+		if !ok {
+			continue
+		}
+		delete(self.effects, hash)
+		self.operations--
+		self.excluded++
+		for _, effect := range effects {
+			self.expected[balanceKey{effect.Address, effect.Asset}] -= effect.Delta
+		}
+	}
+}
+
+func (self *Auditor) check(end map[balanceKey]uint64) *AuditSummary {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	summary := &AuditSummary{
+		Passed:        true,
+		Operations:    self.operations,
+		Excluded:      self.excluded,
+		Discrepancies: make([]*Discrepancy, 0),
+	}
+	for key, start := range self.start {
+		actual := int64(end[key]) - int64(start)
+		if actual == self.expected[key] {
+			continue
+		}
+		summary.Passed = false
+		summary.Discrepancies = append(summary.Discrepancies, &Discrepancy{
+			Address:  key.address.ToBase58(),
+			Asset:    key.asset,
+			Start:    start,
+			End:      end[key],
+			Expected: self.expected[key],
+			Actual:   actual,
+		})
+	}
+	return summary
+}
+
+// Audit poll the balances until they match the expected deltas or timeout
+func (self *Auditor) Audit(client BalanceClient, timeout time.Duration) (*AuditSummary, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		end, err := self.balances(client)
+		if err != nil {
+			return nil, err
+		}
+		summary := self.check(end)
+		if summary.Passed || time.Now().After(deadline) {
+			self.lock.Lock()
+			self.result = summary
+			self.lock.Unlock()
+			return summary, nil
+		}
+		<-time.After(time.Second)
+	}
+}
+
+func (self *Auditor) Summarize(report *Report) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	report.Audit = self.result
+}
+
+func PrintAudit(summary *AuditSummary) {
+	if summary.Passed {
+		fmt.Printf("audit passed, %d operations, %d excluded\n", summary.Operations, summary.Excluded)
+		return
+	}
+	fmt.Printf("AUDIT FAILED, %d operations, %d excluded, %d discrepancies\n",
+		summary.Operations, summary.Excluded, len(summary.Discrepancies))
+	for _, d := range summary.Discrepancies {
+		fmt.Printf("  %s %s start:%d end:%d expected delta:%d actual delta:%d\n",
+			d.Address, d.Asset, d.Start, d.End, d.Expected, d.Actual)
+	}
+}
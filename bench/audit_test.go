@@ -0,0 +1,84 @@
+package bench
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	sdkcom "github.com/ontio/ontology-go-sdk/common"
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+)
+
+// fakeLedger apply the transfers only after a few balance queries, like a
+// node packing them in a later block
+type fakeLedger struct {
+	lock     sync.Mutex
+	balances map[balanceKey]uint64
+	pending  []*Effect
+	queries  int
+}
+
+func (self *fakeLedger) GetBalance(addr common.Address) (*sdkcom.Balance, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.queries++
+	if self.queries%4 == 0 {
+		for _, effect := range self.pending {
+			key := balanceKey{effect.Address, effect.Asset}
+			self.balances[key] = uint64(int64(self.balances[key]) + effect.Delta)
+		}
+		self.pending = nil
+	}
+	return &sdkcom.Balance{
+		Ont: self.balances[balanceKey{addr, OP_ONT_TRANSFER}],
+		Ong: self.balances[balanceKey{addr, OP_ONG_TRANSFER}],
+	}, nil
+}
+
+func (self *fakeLedger) Transfer(gasPrice, gasLimit uint64, asset string, from *account.Account, to common.Address, amount uint64) (common.Uint256, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.pending = append(self.pending, transferEffects(asset, from.Address, to, amount)...)
+	return common.Uint256{}, nil
+}
+
+func TestAuditFunding(t *testing.T) {
+	admin := &account.Account{Address: common.Address{0xff}}
+	senders := newSenders(2)
+	ledger := &fakeLedger{balances: map[balanceKey]uint64{{admin.Address, OP_ONT_TRANSFER}: 1000}}
+	//the reused senders hold enough already, so the funding is still pending
+	//once Fund returns
+	for _, acc := range senders.Accounts() {
+		ledger.balances[balanceKey{acc.Address, OP_ONT_TRANSFER}] = 10
+	}
+	auditor := NewAuditor()
+	addrs := []common.Address{admin.Address}
+	for _, acc := range senders.Accounts() {
+		addrs = append(addrs, acc.Address)
+	}
+	if err := auditor.Snapshot(ledger, addrs); err != nil {
+		t.Fatalf("Snapshot error:%s", err)
+	}
+	if err := senders.Fund(ledger, admin, 5, 0, time.Second, auditor); err != nil {
+		t.Fatalf("Fund error:%s", err)
+	}
+	op := &Operation{Kind: OP_ONT_TRANSFER, amount: 3, to: admin.Address}
+	from := senders.Sender(0)
+	ledger.Transfer(0, 0, OP_ONT_TRANSFER, from, admin.Address, 3)
+	auditor.Record(common.Uint256{1}, op.Effects(from.Address))
+
+	summary, err := auditor.Audit(ledger, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Audit error:%s", err)
+	}
+	if !summary.Passed {
+		for _, d := range summary.Discrepancies {
+			t.Errorf("%s %s expected %d, actual %d", d.Address, d.Asset, d.Expected, d.Actual)
+		}
+		t.Fatal("audit failed")
+	}
+	if summary.Operations != 1 {
+		t.Fatalf("%d operations audited, want 1", summary.Operations)
+	}
+}
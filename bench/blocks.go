@@ -0,0 +1,145 @@
+package bench
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/types"
+)
+
+// BlockClient is the part of the rpc client needed to poll blocks
+type BlockClient interface {
+	GetBlockCount() (uint32, error)
+	GetBlockByHeight(height uint32) (*types.Block, error)
+}
+
+// BlockInfo is a new block seen by the block followers
+type BlockInfo struct {
+	Height    uint32
+	Hashes    []common.Uint256
+	Timestamp time.Time //timestamp of the block header, At if unknown
+	At        time.Time //time the block was observed
+}
+
+// BlockListener is notified of every new block in height order
+type BlockListener interface {
+	OnBlock(block *BlockInfo)
+}
+
+func notify(listeners []BlockListener, block *BlockInfo) {
+	for _, l := range listeners {
+		l.OnBlock(block)
+	}
+}
+
+// PollBlocks follow new blocks by polling the block height every interval
+// until stopCh is closed
+func PollBlocks(client BlockClient, interval time.Duration, stopCh <-chan struct{}, listeners ...BlockListener) error {
+	next, err := client.GetBlockCount()
+	if err != nil {
+		return fmt.Errorf("GetBlockCount error:%s", err)
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				count, err := client.GetBlockCount()
+				if err != nil {
+					fmt.Printf("GetBlockCount error:%s\n", err)
+					continue
+				}
+				for ; next < count; next++ {
+					block, err := client.GetBlockByHeight(next)
+					if err != nil {
+						fmt.Printf("GetBlockByHeight %d error:%s\n", next, err)
+						break
+					}
+					info := &BlockInfo{
+						Height:    next,
+						Hashes:    make([]common.Uint256, 0, len(block.Transactions)),
+						Timestamp: time.Unix(int64(block.Header.Timestamp), 0),
+						At:        time.Now(),
+					}
+					for _, tx := range block.Transactions {
+						info.Hashes = append(info.Hashes, tx.Hash())
+					}
+					notify(listeners, info)
+				}
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// wsBlockTxHashs is the block tx hashes pushed by the node's websocket server
+type wsBlockTxHashs struct {
+	Action string
+	Error  int64
+	Result struct {
+		Hash         string
+		Height       uint32
+		Transactions []string
+	}
+}
+
+// SubscribeBlocks follow new blocks by subscribing the block tx hashes over the
+// node's websocket server at address until stopCh is closed. The pushes carry
+// no block timestamp
+func SubscribeBlocks(address string, stopCh <-chan struct{}, listeners ...BlockListener) error {
+	conn, _, err := websocket.DefaultDialer.Dial(address, nil)
+	if err != nil {
+		return fmt.Errorf("websocket dial %s error:%s", address, err)
+	}
+	err = conn.WriteJSON(map[string]interface{}{
+		"Action":                "subscribe",
+		"Version":               "1.0.0",
+		"SubscribeBlockTxHashs": true,
+	})
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("websocket subscribe error:%s", err)
+	}
+	go func() {
+		<-stopCh
+		conn.Close()
+	}()
+	go func() {
+		for {
+			msg := &wsBlockTxHashs{}
+			if err := conn.ReadJSON(msg); err != nil {
+				select {
+				case <-stopCh:
+				default:
+					fmt.Printf("websocket read error:%s\n", err)
+				}
+				return
+			}
+			if msg.Action != "sendblocktxhashs" || msg.Error != 0 {
+				continue
+			}
+			now := time.Now()
+			info := &BlockInfo{
+				Height:    msg.Result.Height,
+				Hashes:    make([]common.Uint256, 0, len(msg.Result.Transactions)),
+				Timestamp: now,
+				At:        now,
+			}
+			for _, h := range msg.Result.Transactions {
+				hash, err := common.Uint256FromHexString(h)
+				if err != nil {
+					fmt.Printf("invalid tx hash %s:%s\n", h, err)
+					continue
+				}
+				info.Hashes = append(info.Hashes, hash)
+			}
+			notify(listeners, info)
+		}
+	}()
+	return nil
+}
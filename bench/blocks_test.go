@@ -0,0 +1,133 @@
+package bench
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sdk "github.com/ontio/ontology-go-sdk"
+	"github.com/ontio/ontology-stress-test/mock"
+	"github.com/ontio/ontology/common"
+)
+
+// blockFunc adapt a function to a BlockListener
+type blockFunc func(block *BlockInfo)
+
+func (self blockFunc) OnBlock(block *BlockInfo) {
+	self(block)
+}
+
+// testConfirm submit transactions once follow has seen a block, and check the
+// confirmer get all of them confirmed
+func testConfirm(t *testing.T, follow func(node *mock.Node, stopCh <-chan struct{}, listeners ...BlockListener) error) {
+	cfg := mock.DefaultConfig()
+	cfg.BlockTime = 50 * time.Millisecond
+	cfg.MaxBlockTxs = 4
+	node := startNode(t, cfg)
+	defer node.Stop()
+
+	confirmer := NewConfirmer(5 * time.Second)
+	blockCh := make(chan struct{}, 1)
+	first := blockFunc(func(*BlockInfo) {
+		select {
+		case blockCh <- struct{}{}:
+		default:
+		}
+	})
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	if err := follow(node, stopCh, confirmer, first); err != nil {
+		t.Fatalf("follow blocks error:%s", err)
+	}
+	select {
+	case <-blockCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no block followed")
+	}
+
+	client := NewRestClient("http://" + node.RestAddress())
+	const count = 20
+	for i := uint32(1); i <= count; i++ {
+		hash, err := client.SendRawTransaction(newTx(t, i))
+		if err != nil {
+			t.Fatalf("SendRawTransaction %d error:%s", i, err)
+		}
+		confirmer.Track(hash, time.Now())
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	confirmer.Wait(ctx)
+	tracked, confirmed := confirmer.Confirmed()
+	if tracked != count || confirmed != count {
+		t.Fatalf("tracked %d confirmed %d, want %d", tracked, confirmed, count)
+	}
+	if n := len(confirmer.Missing()); n != 0 {
+		t.Fatalf("%d transactions missing", n)
+	}
+}
+
+func TestPollBlocks(t *testing.T) {
+	testConfirm(t, func(node *mock.Node, stopCh <-chan struct{}, listeners ...BlockListener) error {
+		ontSdk := sdk.NewOntologySdk()
+		ontSdk.Rpc.SetAddress("http://" + node.RpcAddress())
+		return PollBlocks(ontSdk.Rpc, 20*time.Millisecond, stopCh, listeners...)
+	})
+}
+
+func TestSubscribeBlocks(t *testing.T) {
+	testConfirm(t, func(node *mock.Node, stopCh <-chan struct{}, listeners ...BlockListener) error {
+		return SubscribeBlocks("ws://"+node.WsAddress(), stopCh, listeners...)
+	})
+}
+
+func TestAnalyzerWarmup(t *testing.T) {
+	analyzer := NewAnalyzer(time.Second, 0)
+	start := time.Now()
+	hashes := make([]common.Uint256, 0, 6)
+	for i := 0; i < 6; i++ {
+		hashes = append(hashes, common.Uint256{byte(i + 1)})
+		if i < 2 {
+			analyzer.SubmitWarmup(hashes[i])
+		} else {
+			analyzer.Submit(start)
+		}
+	}
+	analyzer.OnBlock(&BlockInfo{Height: 1, Timestamp: start, At: start, Hashes: hashes[:3]})
+	analyzer.OnBlock(&BlockInfo{Height: 2, Timestamp: start.Add(time.Second), At: start, Hashes: hashes[3:]})
+
+	report := &Report{}
+	analyzer.Summarize(report)
+	sum := report.Blocks
+	if sum.Committed != 4 || sum.Submitted != 4 || sum.FinalLag != 0 || sum.MaxLag != 3 {
+		t.Fatalf("committed %d submitted %d lag max %d final %d, want 4 4 3 0",
+			sum.Committed, sum.Submitted, sum.MaxLag, sum.FinalLag)
+	}
+	if sum.CommittedTPS != 3 || sum.MeanTxs != 3 {
+		t.Fatalf("committed tps %v mean txs %v, want 3 3", sum.CommittedTPS, sum.MeanTxs)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	analyzer.Wait(ctx, time.Minute)
+	if ctx.Err() != nil {
+		t.Fatal("Wait not done with every submission committed")
+	}
+}
+
+func TestSettle(t *testing.T) {
+	cfg := mock.DefaultConfig()
+	cfg.BlockTime = 50 * time.Millisecond
+	node := startNode(t, cfg)
+	ontSdk := sdk.NewOntologySdk()
+	ontSdk.Rpc.SetAddress("http://" + node.RpcAddress())
+	start := node.BlockCount()
+	if err := newSenders(1).Settle(ontSdk.Rpc, SWEEP_BLOCKS, 10*time.Second); err != nil {
+		t.Fatalf("Settle error:%s", err)
+	}
+	if count := node.BlockCount(); count < start+SWEEP_BLOCKS {
+		t.Fatalf("settled at block count %d, want at least %d", count, start+SWEEP_BLOCKS)
+	}
+	node.Stop()
+	if err := newSenders(1).Settle(ontSdk.Rpc, SWEEP_BLOCKS, time.Second); err == nil {
+		t.Fatal("settled without new blocks")
+	}
+}
@@ -0,0 +1,145 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ontio/ontology-crypto/keypair"
+	s "github.com/ontio/ontology-crypto/signature"
+)
+
+// consensus types of the node config
+const (
+	CONSENSUS_SOLO = "solo"
+	CONSENSUS_DBFT = "dbft"
+)
+
+// SenderKey return the key parameters of the accounts of keyType
+func SenderKey(keyType string) (keypair.KeyType, byte, s.SignatureScheme, error) {
+	switch keyType {
+	case KEY_TYPE_ECDSA:
+		return keypair.PK_ECDSA, keypair.P256, s.SHA256withECDSA, nil
+	case KEY_TYPE_SM2:
+		return keypair.PK_SM2, keypair.SM2P256V1, s.SM3withSM2, nil
+	case KEY_TYPE_ED25519:
+		return keypair.PK_EDDSA, keypair.ED25519, s.SHA512withEDDSA, nil
+	}
+	return 0, 0, 0, fmt.Errorf("unknown key type %s", keyType)
+}
+
+// WriteBookkeepers set the bookkeepers and the consensus type of the node
+// config file. Only the two values are replaced, the other settings, the key
+// order and the byte order mark are kept
+func WriteBookkeepers(file, consensus string, pubkeys []string) error {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("read config error:%s", err)
+	}
+	//leave the UTF-8 Byte Order Mark in front of the config
+	body := bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	start, end, err := valueSpan(body, "Configuration")
+	if err != nil {
+		return fmt.Errorf("parse config error:%s", err)
+	}
+	if start < 0 {
+		return fmt.Errorf("no Configuration in %s", file)
+	}
+	node := body[start:end]
+	if node[0] != '{' {
+		return fmt.Errorf("Configuration of %s is not an object", file)
+	}
+	keepers, err := json.MarshalIndent(pubkeys, keyIndent(node, "Bookkeepers"), "  ")
+	if err != nil {
+		return err
+	}
+	node, err = setValue(node, "Bookkeepers", keepers)
+	if err != nil {
+		return fmt.Errorf("parse Configuration error:%s", err)
+	}
+	value, err := json.Marshal(consensus)
+	if err != nil {
+		return err
+	}
+	node, err = setValue(node, "ConsensusType", value)
+	if err != nil {
+		return fmt.Errorf("parse Configuration error:%s", err)
+	}
+	out := make([]byte, 0, len(data)+len(node))
+	out = append(out, data[:len(data)-len(body)]...)
+	out = append(out, body[:start]...)
+	out = append(out, node...)
+	out = append(out, body[end:]...)
+	return ioutil.WriteFile(file, out, 0644)
+}
+
+// valueSpan return the offsets of the value of key in the json object obj,
+// -1 if obj has no key
+func valueSpan(obj []byte, key string) (int, int, error) {
+	dec := json.NewDecoder(bytes.NewReader(obj))
+	token, err := dec.Token()
+	if err != nil {
+		return 0, 0, err
+	}
+	if token != json.Delim('{') {
+		return 0, 0, fmt.Errorf("not a json object")
+	}
+	for dec.More() {
+		token, err = dec.Token()
+		if err != nil {
+			return 0, 0, err
+		}
+		raw := json.RawMessage{}
+		err = dec.Decode(&raw)
+		if err != nil {
+			return 0, 0, err
+		}
+		if token == key {
+			end := int(dec.InputOffset())
+			return end - len(raw), end, nil
+		}
+	}
+	return -1, -1, nil
+}
+
+// setValue replace the value of key in the json object obj with value, the
+// key is appended if obj has none
+func setValue(obj []byte, key string, value []byte) ([]byte, error) {
+	start, end, err := valueSpan(obj, key)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]byte, 0, len(obj)+len(key)+len(value))
+	if start >= 0 {
+		ret = append(ret, obj[:start]...)
+		ret = append(ret, value...)
+		return append(ret, obj[end:]...), nil
+	}
+	//insert before the closing brace, after the last value
+	brace := bytes.LastIndexByte(obj, '}')
+	last := len(bytes.TrimRight(obj[:brace], " \t\r\n"))
+	ret = append(ret, obj[:last]...)
+	if obj[last-1] != '{' {
+		ret = append(ret, ',')
+	}
+	indent := keyIndent(obj, "")
+	ret = append(ret, fmt.Sprintf("\n%s%q: %s", indent, key, value)...)
+	return append(ret, obj[last:]...), nil
+}
+
+// keyIndent return the indentation of the line of key in obj, the one of the
+// closing brace plus two spaces if key is empty or not found
+func keyIndent(obj []byte, key string) string {
+	pos := -1
+	if key != "" {
+		pos = bytes.Index(obj, []byte(fmt.Sprintf("%q", key)))
+	}
+	extra := ""
+	if pos < 0 {
+		pos, extra = bytes.LastIndexByte(obj, '}'), "  "
+	}
+	line := bytes.LastIndexByte(obj[:pos], '\n') + 1
+	indent := obj[line:pos]
+	return string(indent[:len(indent)-len(bytes.TrimLeft(indent, " \t"))]) + extra
+}
@@ -0,0 +1,94 @@
+package bench
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfig = "\xef\xbb\xbf\n{\n" +
+	"  \"Configuration\": {\n" +
+	"    \"Magic\": 7630401,\n" +
+	"    \"Bookkeepers\": [\n" +
+	"      \"old\"\n" +
+	"    ],\n" +
+	"    \"HttpWsPort\":30335,\n" +
+	"    \"ConsensusType\":\"solo\"\n" +
+	"  }\n" +
+	"}\n"
+
+func writeConfig(t *testing.T, text string) string {
+	file := filepath.Join(t.TempDir(), "config.json")
+	if err := ioutil.WriteFile(file, []byte(text), 0644); err != nil {
+		t.Fatalf("WriteFile error:%s", err)
+	}
+	return file
+}
+
+func readConfig(t *testing.T, file string) string {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("ReadFile error:%s", err)
+	}
+	return string(data)
+}
+
+func TestWriteBookkeepers(t *testing.T) {
+	file := writeConfig(t, testConfig)
+	err := WriteBookkeepers(file, CONSENSUS_DBFT, []string{"k1", "k2"})
+	if err != nil {
+		t.Fatalf("WriteBookkeepers error:%s", err)
+	}
+	want := "\xef\xbb\xbf\n{\n" +
+		"  \"Configuration\": {\n" +
+		"    \"Magic\": 7630401,\n" +
+		"    \"Bookkeepers\": [\n" +
+		"      \"k1\",\n" +
+		"      \"k2\"\n" +
+		"    ],\n" +
+		"    \"HttpWsPort\":30335,\n" +
+		"    \"ConsensusType\":\"dbft\"\n" +
+		"  }\n" +
+		"}\n"
+	if got := readConfig(t, file); got != want {
+		t.Fatalf("config is\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteBookkeepersAppend(t *testing.T) {
+	text := strings.Replace(testConfig, ",\n    \"ConsensusType\":\"solo\"", "", 1)
+	file := writeConfig(t, text)
+	err := WriteBookkeepers(file, CONSENSUS_SOLO, []string{"k1"})
+	if err != nil {
+		t.Fatalf("WriteBookkeepers error:%s", err)
+	}
+	got := readConfig(t, file)
+	if !strings.HasPrefix(got, "\xef\xbb\xbf") {
+		t.Fatal("byte order mark dropped")
+	}
+	if !strings.Contains(got, "\"HttpWsPort\":30335,\n    \"ConsensusType\": \"solo\"\n  }") {
+		t.Fatalf("ConsensusType not appended after the last setting:\n%s", got)
+	}
+	cfg := struct {
+		Configuration struct {
+			Bookkeepers   []string
+			ConsensusType string
+		}
+	}{}
+	if err = json.Unmarshal([]byte(strings.TrimPrefix(got, "\xef\xbb\xbf")), &cfg); err != nil {
+		t.Fatalf("Unmarshal error:%s", err)
+	}
+	if len(cfg.Configuration.Bookkeepers) != 1 || cfg.Configuration.Bookkeepers[0] != "k1" {
+		t.Fatalf("bookkeepers %v", cfg.Configuration.Bookkeepers)
+	}
+}
+
+func TestWriteBookkeepersInvalid(t *testing.T) {
+	for _, text := range []string{"{\"Other\": {}}", "{\"Configuration\": [1]}", "{\"Configuration\": {"} {
+		if err := WriteBookkeepers(writeConfig(t, text), CONSENSUS_SOLO, nil); err == nil {
+			t.Fatalf("config %s accepted", text)
+		}
+	}
+}
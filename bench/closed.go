@@ -0,0 +1,54 @@
+package bench
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// models of the bench
+const (
+	MODEL_OPEN   = "open"   //tasks released at the target rate whatever the response time
+	MODEL_CLOSED = "closed" //fixed virtual users each wait for the response before the next
+)
+
+// ClosedLoop issue the tasks of the closed model, every virtual user take
+// the next task only after the response of the previous one and a think time
+type ClosedLoop struct {
+	seq   uint64
+	total int
+	think time.Duration
+}
+
+// NewClosedLoop return a ClosedLoop issuing total tasks, 0 means unlimited
+func NewClosedLoop(total int, think time.Duration) *ClosedLoop {
+	return &ClosedLoop{total: total, think: think}
+}
+
+// Next return the next task of a virtual user, false if all the tasks are
+// issued or ctx is done. The task is intended to be sent right now
+func (self *ClosedLoop) Next(ctx context.Context) (*Task, bool) {
+	if ctx.Err() != nil {
+		return nil, false
+	}
+	seq := atomic.AddUint64(&self.seq, 1) - 1
+	if self.total > 0 && seq >= uint64(self.total) {
+		return nil, false
+	}
+	return &Task{Seq: seq, Intended: time.Now()}, true
+}
+
+// Think wait the think time of a virtual user, return false if ctx is done
+func (self *ClosedLoop) Think(ctx context.Context) bool {
+	if self.think <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(self.think)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,103 @@
+package bench
+
+import (
+	"fmt"
+)
+
+// Tolerance of the regression check
+type Tolerance struct {
+	TPS       float64 //max drop of achieved tps, percent
+	Latency   float64 //max growth of latency percentiles, percent
+	ErrorRate float64 //max growth of error rate, percentage points
+}
+
+// Diff is the change of one metric between two reports
+type Diff struct {
+	Metric    string
+	Base      float64
+	Current   float64
+	Change    float64 //percent, percentage points for rates
+	Regressed bool
+}
+
+func errorRate(s *Summary) float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Errors) * 100 / float64(s.Requests)
+}
+
+func percentChange(base, current float64) float64 {
+	if base == 0 {
+		if current == 0 {
+			return 0
+		}
+		return 100
+	}
+	return (current - base) * 100 / base
+}
+
+// Compare diff current against base and mark the metrics which regress
+// beyond tol
+func Compare(base, current *Report, tol *Tolerance) []*Diff {
+	diffs := make([]*Diff, 0)
+	change := percentChange(base.Summary.TPS, current.Summary.TPS)
+	diffs = append(diffs, &Diff{
+		Metric:    "tps",
+		Base:      base.Summary.TPS,
+		Current:   current.Summary.TPS,
+		Change:    change,
+		Regressed: -change > tol.TPS,
+	})
+	baseRate, curRate := errorRate(base.Summary), errorRate(current.Summary)
+	diffs = append(diffs, &Diff{
+		Metric:    "error rate",
+		Base:      baseRate,
+		Current:   curRate,
+		Change:    curRate - baseRate,
+		Regressed: curRate-baseRate > tol.ErrorRate,
+	})
+	latency := func(name string, base, current *LatencySummary) {
+		if base == nil || current == nil {
+			return
+		}
+		for _, p := range []struct {
+			name          string
+			base, current float64
+		}{
+			{"p50", base.P50, current.P50},
+			{"p90", base.P90, current.P90},
+			{"p99", base.P99, current.P99},
+			{"p99.9", base.P999, current.P999},
+		} {
+			change := percentChange(p.base, p.current)
+			diffs = append(diffs, &Diff{
+				Metric:    name + " " + p.name,
+				Base:      p.base,
+				Current:   p.current,
+				Change:    change,
+				Regressed: change > tol.Latency,
+			})
+		}
+	}
+	latency("latency", base.Latency, current.Latency)
+	if base.Confirm != nil && current.Confirm != nil {
+		latency("confirm", base.Confirm.Latency, current.Confirm.Latency)
+	}
+	return diffs
+}
+
+// PrintDiffs print diffs and return the number of regressed metrics
+func PrintDiffs(diffs []*Diff) int {
+	regressed := 0
+	fmt.Printf("%-16s %14s %14s %10s\n", "metric", "base", "current", "change")
+	for _, diff := range diffs {
+		mark := ""
+		if diff.Regressed {
+			mark = "REGRESSED"
+			regressed++
+		}
+		fmt.Printf("%-16s %14.3f %14.3f %+9.2f%% %s\n", diff.Metric, diff.Base, diff.Current, diff.Change, mark)
+	}
+	return regressed
+}
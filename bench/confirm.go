@@ -0,0 +1,239 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ontio/ontology/common"
+)
+
+// max missing tx hashes printed by the report
+const MAX_PRINT_MISSING = 20
+
+// Confirmer follow new blocks and match the tx hashes included in them with
+// the submitted transactions to measure the submit to block latency.
+type Confirmer struct {
+	lock      sync.Mutex
+	deadline  time.Duration
+	pending   map[common.Uint256]time.Time //hash -> submit time
+	seen      map[common.Uint256]time.Time //hash in block but not tracked yet
+	warm      map[common.Uint256]bool      //pending hashes of the warmup
+	rejected  map[common.Uint256]bool      //pending hashes whose submission failed
+	missing   []common.Uint256
+	warmLost  []common.Uint256 //warmup transactions never included
+	tracked   uint64
+	confirmed uint64
+	landed    uint64 //transactions included although their submission failed
+	height    uint32
+	latency   *Histogram
+}
+
+// NewConfirmer return a Confirmer which give up a transaction not included in
+// a block deadline after its submission
+func NewConfirmer(deadline time.Duration) *Confirmer {
+	return &Confirmer{
+		deadline: deadline,
+		pending:  make(map[common.Uint256]time.Time),
+		seen:     make(map[common.Uint256]time.Time),
+		warm:     make(map[common.Uint256]bool),
+		rejected: make(map[common.Uint256]bool),
+		latency:  NewHistogram(),
+	}
+}
+
+// Track record a transaction submitted at submit
+func (self *Confirmer) Track(hash common.Uint256, submit time.Time) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.tracked++
+	if at, ok := self.seen[hash]; ok {
+		delete(self.seen, hash)
+		self.confirm(submit, at)
+		return
+	}
+	self.pending[hash] = submit
+}
+
+// TrackWarmup follow a transaction of the warmup submitted at submit, it is
+// left out of the counts and the latency and only checked for inclusion
+func (self *Confirmer) TrackWarmup(hash common.Uint256, submit time.Time) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if _, ok := self.seen[hash]; ok {
+		delete(self.seen, hash)
+		return
+	}
+	self.pending[hash] = submit
+	self.warm[hash] = true
+}
+
+// TrackRejected follow a transaction whose submission failed at submit, a
+// timed out one may still be packed. It is left out of the counts and only
+// checked for inclusion
+func (self *Confirmer) TrackRejected(hash common.Uint256, submit time.Time) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if _, ok := self.seen[hash]; ok {
+		delete(self.seen, hash)
+		self.landed++
+		return
+	}
+	self.pending[hash] = submit
+	self.rejected[hash] = true
+}
+
+func (self *Confirmer) confirm(submit, at time.Time) {
+	self.confirmed++
+	self.latency.Record(at.Sub(submit))
+	metricConfirm(at.Sub(submit))
+}
+
+// OnBlock match the tx hashes of the block with the tracked transactions
+func (self *Confirmer) OnBlock(block *BlockInfo) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if block.Height > self.height {
+		self.height = block.Height
+	}
+	for _, hash := range block.Hashes {
+		submit, ok := self.pending[hash]
+		if !ok {
+			self.seen[hash] = block.At
+			continue
+		}
+		delete(self.pending, hash)
+		if self.warm[hash] {
+			delete(self.warm, hash)
+			continue
+		}
+		if self.rejected[hash] {
+			delete(self.rejected, hash)
+			self.landed++
+			continue
+		}
+		self.confirm(submit, block.At)
+	}
+	//the hashes of other senders would pile up over a long run
+	self.prune(block.At)
+}
+
+// expire move the pending transactions out of deadline to missing and drop
+// the block hashes seen deadline ago which were never tracked, return the
+// number of transactions still pending
+func (self *Confirmer) expire(now time.Time) int {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	for hash, submit := range self.pending {
+		if now.Sub(submit) > self.deadline {
+			delete(self.pending, hash)
+			if self.rejected[hash] {
+				delete(self.rejected, hash)
+				continue
+			}
+			if self.warm[hash] {
+				delete(self.warm, hash)
+				self.warmLost = append(self.warmLost, hash)
+				continue
+			}
+			self.missing = append(self.missing, hash)
+		}
+	}
+	self.prune(now)
+	return len(self.pending)
+}
+
+// prune drop the block hashes seen deadline before now, the lock is held
+func (self *Confirmer) prune(now time.Time) {
+	for hash, at := range self.seen {
+		if now.Sub(at) > self.deadline {
+			delete(self.seen, hash)
+		}
+	}
+}
+
+// Wait block until every tracked transaction is confirmed or out of deadline,
+// or ctx is done
+func (self *Confirmer) Wait(ctx context.Context) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for self.expire(time.Now()) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (self *Confirmer) Latency() *Histogram {
+	return self.latency
+}
+
+// Confirmed return the tracked and confirmed transaction count
+func (self *Confirmer) Confirmed() (uint64, uint64) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.tracked, self.confirmed
+}
+
+// Landed return the number of transactions included although their submission
+// failed
+func (self *Confirmer) Landed() uint64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.landed
+}
+
+// Missing return the transactions not included in a block before deadline
+func (self *Confirmer) Missing() []common.Uint256 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	missing := make([]common.Uint256, len(self.missing))
+	copy(missing, self.missing)
+	return missing
+}
+
+// WarmupMissing return the warmup transactions not included in a block before
+// deadline
+func (self *Confirmer) WarmupMissing() []common.Uint256 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	missing := make([]common.Uint256, len(self.warmLost))
+	copy(missing, self.warmLost)
+	return missing
+}
+
+func (self *Confirmer) Print() {
+	tracked, confirmed := self.Confirmed()
+	missing := self.Missing()
+	self.lock.Lock()
+	height := self.height
+	self.lock.Unlock()
+	fmt.Printf("confirmed %d/%d, missing:%d, height:%d, failed submissions included:%d\n",
+		confirmed, tracked, len(missing), height, self.Landed())
+	fmt.Printf("confirm     %s\n", formatHistogram(self.latency))
+	for i, hash := range missing {
+		if i == MAX_PRINT_MISSING {
+			fmt.Printf("... %d more missing\n", len(missing)-i)
+			break
+		}
+		fmt.Printf("missing tx:%s\n", hash.ToHexString())
+	}
+}
+
+func (self *Confirmer) Summarize(report *Report) {
+	tracked, confirmed := self.Confirmed()
+	sum := &ConfirmSummary{
+		Tracked:   tracked,
+		Confirmed: confirmed,
+		Landed:    self.Landed(),
+		Missing:   make([]string, 0),
+		Latency:   Summarize(self.latency),
+	}
+	for _, hash := range self.Missing() {
+		sum.Missing = append(sum.Missing, hash.ToHexString())
+	}
+	report.Confirm = sum
+}
@@ -0,0 +1,491 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// interval of polling the state of the agents
+const AGENT_POLL_INTERVAL = 500 * time.Millisecond
+
+// Coordinator drive the agents of a distributed run through the plan, the
+// start barrier and the collection of the results
+type Coordinator struct {
+	agents []string
+	client *http.Client
+}
+
+// NewCoordinator return the Coordinator of the agents at addresses
+func NewCoordinator(addresses []string) *Coordinator {
+	agents := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		if !strings.HasPrefix(address, "http://") {
+			address = "http://" + address
+		}
+		agents = append(agents, strings.TrimRight(address, "/"))
+	}
+	return &Coordinator{agents: agents, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+func (self *Coordinator) Agents() []string {
+	return self.agents
+}
+
+func (self *Coordinator) request(i int, method, path string, body interface{}, ret interface{}) error {
+	var reader *bytes.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequest(method, self.agents[i]+path, reader)
+	if err != nil {
+		return err
+	}
+	resp, err := self.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("agent %s error:%s", self.agents[i], err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("agent %s %s error:%s", self.agents[i], path, strings.TrimSpace(string(msg)))
+	}
+	if ret != nil {
+		return json.NewDecoder(resp.Body).Decode(ret)
+	}
+	return nil
+}
+
+// each call f on every agent concurrently, return the first error
+func (self *Coordinator) each(f func(i int) error) error {
+	errs := make([]error, len(self.agents))
+	wg := &sync.WaitGroup{}
+	for i := range self.agents {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = f(i)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Assign send plans[i] to the i-th agent
+func (self *Coordinator) Assign(plans []*Plan) error {
+	return self.each(func(i int) error {
+		return self.request(i, http.MethodPost, "/plan", plans[i], nil)
+	})
+}
+
+// Wait block until every agent is in state, fail if an agent failed or ctx
+// is done
+func (self *Coordinator) Wait(ctx context.Context, state string) error {
+	ticker := time.NewTicker(AGENT_POLL_INTERVAL)
+	defer ticker.Stop()
+	for {
+		reached := 0
+		for i := range self.agents {
+			status := &AgentStatus{}
+			err := self.request(i, http.MethodGet, "/status", nil, status)
+			if err != nil {
+				return err
+			}
+			if status.State == AGENT_FAILED {
+				return fmt.Errorf("agent %s failed:%s", self.agents[i], status.Error)
+			}
+			if status.State == state {
+				reached++
+			}
+		}
+		if reached == len(self.agents) {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Start release the agents waiting at the start barrier
+func (self *Coordinator) Start() error {
+	return self.each(func(i int) error {
+		return self.request(i, http.MethodPost, "/start", nil, nil)
+	})
+}
+
+// Stop ask the agents to stop their run
+func (self *Coordinator) Stop() error {
+	return self.each(func(i int) error {
+		return self.request(i, http.MethodPost, "/stop", nil, nil)
+	})
+}
+
+// Results fetch the results of the agents
+func (self *Coordinator) Results() ([]*AgentResult, error) {
+	results := make([]*AgentResult, len(self.agents))
+	err := self.each(func(i int) error {
+		results[i] = &AgentResult{}
+		return self.request(i, http.MethodGet, "/result", nil, results[i])
+	})
+	return results, err
+}
+
+// merged histograms by name
+type histSet map[string]*Histogram
+
+func (self histSet) get(name string) *Histogram {
+	h, ok := self[name]
+	if !ok {
+		h = NewHistogram()
+		self[name] = h
+	}
+	return h
+}
+
+// MergeResults merge the results of the agents into report. Counters are
+// summed and percentiles come from the merged histograms, the send lag of
+// the agents can not be merged and is left out. The block series add up the
+// submitted counts of the agents, the mempool series is the one of the first
+// agent
+func MergeResults(report *Report, results []*AgentResult) {
+	hists := make(histSet)
+	for _, result := range results {
+		for name, h := range result.Histograms {
+			hists.get(name).Merge(h)
+		}
+	}
+	report.Summary = &Summary{}
+	report.Kinds = make(map[string]*OpSummary)
+	report.Errors = make(map[string]*ErrorSummary)
+	endpoints := make(map[string]*EndpointSummary)
+	aborted := make([]string, 0)
+	signTime := 0.0 //worker seconds of the signing stages
+	blocks := make([]*BlockSummary, 0)
+	for _, result := range results {
+		r := result.Report
+		if report.Start.IsZero() || r.Start.Before(report.Start) {
+			report.Start = r.Start
+		}
+		if r.End.After(report.End) {
+			report.End = r.End
+		}
+		report.Interrupted = report.Interrupted || r.Interrupted
+		if r.Aborted != "" {
+			aborted = append(aborted, fmt.Sprintf("agent %d:%s", result.Index, r.Aborted))
+		}
+		report.Transport, report.Model = r.Transport, r.Model
+		report.Concurrency += r.Concurrency
+		report.Summary.Success += r.Summary.Success
+		report.Summary.Errors += r.Summary.Errors
+		for kind, op := range r.Kinds {
+			sum, ok := report.Kinds[kind]
+			if !ok {
+				sum = &OpSummary{}
+				report.Kinds[kind] = sum
+			}
+			sum.Success += op.Success
+			sum.Error += op.Error
+		}
+		for class, e := range r.Errors {
+			sum, ok := report.Errors[class]
+			if !ok {
+				sum = &ErrorSummary{}
+				report.Errors[class] = sum
+			}
+			sum.Count += e.Count
+			for _, sample := range e.Samples {
+				if len(sum.Samples) < MAX_ERROR_SAMPLES && !containString(sum.Samples, sample) {
+					sum.Samples = append(sum.Samples, sample)
+				}
+			}
+		}
+		for i, phase := range r.Phases {
+			if i == len(report.Phases) {
+				report.Phases = append(report.Phases, &PhaseSummary{Name: phase.Name, Start: phase.Start})
+			}
+			sum := report.Phases[i]
+			if phase.Seconds > sum.Seconds {
+				sum.Seconds = phase.Seconds
+			}
+			sum.Target += phase.Target
+			sum.TPS += phase.TPS
+			sum.Success += phase.Success
+			sum.Error += phase.Error
+		}
+		for _, ep := range r.Endpoints {
+			sum, ok := endpoints[ep.Address]
+			if !ok {
+				sum = &EndpointSummary{Address: ep.Address}
+				endpoints[ep.Address] = sum
+			}
+			sum.Success += ep.Success
+			sum.Error += ep.Error
+			sum.Ejections += ep.Ejections
+		}
+		if r.Confirm != nil {
+			if report.Confirm == nil {
+				report.Confirm = &ConfirmSummary{Missing: make([]string, 0)}
+			}
+			report.Confirm.Tracked += r.Confirm.Tracked
+			report.Confirm.Confirmed += r.Confirm.Confirmed
+			report.Confirm.Landed += r.Confirm.Landed
+			report.Confirm.Missing = append(report.Confirm.Missing, r.Confirm.Missing...)
+		}
+		if r.Blocks != nil {
+			blocks = append(blocks, r.Blocks)
+		}
+		if r.Mempool != nil && report.Mempool == nil {
+			report.Mempool = r.Mempool
+		}
+		if r.Warmup != nil {
+			if report.Warmup == nil {
+				report.Warmup = &WindowSummary{Start: r.Warmup.Start}
+			}
+			w := report.Warmup
+			if r.Warmup.Start.Before(w.Start) {
+				w.Start = r.Warmup.Start
+			}
+			if r.Warmup.End.After(w.End) {
+				w.End = r.Warmup.End
+			}
+			w.Requests += r.Warmup.Requests
+			w.Success += r.Warmup.Success
+			w.Errors += r.Warmup.Errors
+		}
+		if r.Signing != nil {
+			if report.Signing == nil {
+				report.Signing = &SignSummary{}
+			}
+			report.Signing.Workers += r.Signing.Workers
+			report.Signing.Signed += r.Signing.Signed
+			report.Signing.Errors += r.Signing.Errors
+			report.Signing.TPS += r.Signing.TPS
+			report.Signing.Busy += r.Signing.Busy
+			report.Signing.Capacity += r.Signing.Capacity
+			signTime += r.Signing.Seconds * float64(r.Signing.Workers)
+			if r.Signing.Seconds > report.Signing.Seconds {
+				report.Signing.Seconds = r.Signing.Seconds
+			}
+		}
+	}
+	report.Aborted = strings.Join(aborted, ", ")
+	report.Blocks = mergeBlocks(blocks)
+	if report.Signing != nil && signTime > 0 {
+		report.Signing.Usage = report.Signing.Busy / signTime
+	}
+
+	summary := report.Summary
+	summary.Requests = summary.Success + summary.Errors
+	summary.Seconds = report.End.Sub(report.Start).Seconds()
+	if summary.Seconds > 0 {
+		summary.TPS = float64(summary.Success) / summary.Seconds
+	}
+	if w := report.Warmup; w != nil {
+		w.Seconds = w.End.Sub(w.Start).Seconds()
+		if w.Seconds > 0 {
+			w.TPS = float64(w.Success) / w.Seconds
+		}
+		w.Latency = Summarize(hists.get("warmup"))
+	}
+	report.Latency = Summarize(hists.get("latency"))
+	report.Service = Summarize(hists.get("service"))
+	for kind, sum := range report.Kinds {
+		sum.Latency = Summarize(hists.get("kind/" + kind))
+	}
+	for i, sum := range report.Phases {
+		sum.Latency = Summarize(hists.get("phase/" + strconv.Itoa(i)))
+	}
+	addresses := make([]string, 0, len(endpoints))
+	for address := range endpoints {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	for _, address := range addresses {
+		sum := endpoints[address]
+		sum.Latency = Summarize(hists.get("endpoint/" + address))
+		report.Endpoints = append(report.Endpoints, sum)
+	}
+	if report.Confirm != nil {
+		report.Confirm.Latency = Summarize(hists.get("confirm"))
+	}
+	if report.Signing != nil {
+		report.Signing.Latency = Summarize(hists.get("sign"))
+	}
+	for i := 0; ; i++ {
+		h, ok := hists["second/"+strconv.Itoa(i)]
+		if !ok {
+			break
+		}
+		report.Series = append(report.Series, summarizeSecond(i, h))
+	}
+}
+
+// rowAt return the last row of series at or below height, nil if none, and
+// the warmup transactions among the ones of the blocks up to it
+func rowAt(series []*BlockRow, height uint32) (*BlockRow, uint64) {
+	var ret *BlockRow
+	txs := uint64(0)
+	for _, row := range series {
+		if row.Height > height {
+			break
+		}
+		ret = row
+		txs += uint64(row.Txs)
+	}
+	if ret == nil {
+		return nil, 0
+	}
+	return ret, txs - ret.Committed
+}
+
+// mergeBlocks merge the block summaries of the agents, nil if there is none.
+// Every agent follow the same chain, the longest series is kept and its
+// submitted counts are the sums of the agents at every height. The committed
+// counts of an agent leave out its own warmup only, so the warmup of every
+// agent is taken out of the transactions of the blocks
+func mergeBlocks(blocks []*BlockSummary) *BlockSummary {
+	if len(blocks) == 0 {
+		return nil
+	}
+	base := blocks[0]
+	for _, b := range blocks[1:] {
+		if len(b.Series) > len(base.Series) {
+			base = b
+		}
+	}
+	sum := *base
+	sum.Submitted, sum.SubmittedTPS, sum.MaxLag, sum.FinalLag = 0, 0, 0, 0
+	for _, b := range blocks {
+		sum.Submitted += b.Submitted
+		sum.SubmittedTPS += b.SubmittedTPS
+	}
+	sum.Series = make([]*BlockRow, 0, len(base.Series))
+	txs := uint64(0)
+	for _, row := range base.Series {
+		txs += uint64(row.Txs)
+		merged := *row
+		merged.Submitted, merged.Committed = 0, txs
+		for _, b := range blocks {
+			r, warm := rowAt(b.Series, row.Height)
+			if r == nil {
+				continue
+			}
+			merged.Submitted += r.Submitted
+			//only the warmup in the blocks of the kept series count
+			if first := base.Series[0].Height; first > 0 {
+				_, before := rowAt(b.Series, first-1)
+				warm -= before
+			}
+			merged.Committed -= warm
+		}
+		merged.Lag = int64(merged.Submitted) - int64(merged.Committed)
+		if merged.Lag > sum.MaxLag {
+			sum.MaxLag = merged.Lag
+		}
+		sum.Series = append(sum.Series, &merged)
+	}
+	if n := len(sum.Series); n > 0 {
+		sum.Committed = sum.Series[n-1].Committed
+		sum.FinalLag = sum.Series[n-1].Lag
+		sum.CommittedTPS = 0
+		if n > 1 {
+			seconds := sum.Series[n-1].Time.Sub(sum.Series[0].Time).Seconds()
+			if seconds > 0 {
+				sum.CommittedTPS = float64(sum.Committed-sum.Series[0].Committed) / seconds
+			}
+		}
+	}
+	sum.Limit = blockLimit(&sum)
+	return &sum
+}
+
+func containString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// PrintReport print the summary of a merged report
+func PrintReport(report *Report) {
+	s := report.Summary
+	if w := report.Warmup; w != nil {
+		fmt.Printf("warmup requests:%d, success:%d, errors:%d, seconds:%.1f, excluded from the statistics\n",
+			w.Requests, w.Success, w.Errors, w.Seconds)
+	}
+	fmt.Printf("requests:%d, success:%d, errors:%d, seconds:%.1f, tps:%.1f\n",
+		s.Requests, s.Success, s.Errors, s.Seconds, s.TPS)
+	printLatency := func(name string, l *LatencySummary) {
+		if l == nil {
+			return
+		}
+		fmt.Printf("%-11s count:%d, mean:%.3fms, p50:%.3fms, p90:%.3fms, p99:%.3fms, p99.9:%.3fms, max:%.3fms\n",
+			name, l.Count, l.Mean, l.P50, l.P90, l.P99, l.P999, l.Max)
+	}
+	printLatency("latency", report.Latency)
+	printLatency("service", report.Service)
+	if report.Confirm != nil {
+		fmt.Printf("confirmed %d of %d, %d missing\n", report.Confirm.Confirmed, report.Confirm.Tracked, len(report.Confirm.Missing))
+		printLatency("confirm", report.Confirm.Latency)
+	}
+	if b := report.Blocks; b != nil {
+		fmt.Printf("blocks:%d, committed:%d, committed tps:%.1f, submitted tps:%.1f, max lag:%d\n",
+			b.Blocks, b.Committed, b.CommittedTPS, b.SubmittedTPS, b.MaxLag)
+		fmt.Println(b.Limit)
+	}
+	if m := report.Mempool; m != nil {
+		fmt.Printf("mempool mean depth:%.1f, peak:%d, saturated:%v\n", m.Mean, m.Peak, m.Saturated)
+	}
+	if report.Signing != nil {
+		fmt.Printf("signed %d by %d workers, %.1f tx/s, %d errors, busy %.1f%%, capacity %.1f tx/s\n",
+			report.Signing.Signed, report.Signing.Workers, report.Signing.TPS, report.Signing.Errors,
+			report.Signing.Usage*100, report.Signing.Capacity)
+	}
+	kinds := make([]string, 0, len(report.Kinds))
+	for kind := range report.Kinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	fmt.Printf("%-12s %8s %8s %12s %12s %12s\n", "kind", "success", "error", "p50(ms)", "p99(ms)", "max(ms)")
+	for _, kind := range kinds {
+		op := report.Kinds[kind]
+		fmt.Printf("%-12s %8d %8d %12.3f %12.3f %12.3f\n", kind, op.Success, op.Error,
+			op.Latency.P50, op.Latency.P99, op.Latency.Max)
+	}
+	classes := make([]string, 0, len(report.Errors))
+	for class := range report.Errors {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		e := report.Errors[class]
+		fmt.Printf("error %-12s %8d\n", class, e.Count)
+		for _, sample := range e.Samples {
+			fmt.Printf("    %s\n", sample)
+		}
+	}
+}
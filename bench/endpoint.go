@@ -0,0 +1,200 @@
+package bench
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ontio/ontology/account"
+)
+
+// load balance strategies of the EndpointPool
+const (
+	LB_ROUND_ROBIN = "roundrobin"
+	LB_RANDOM      = "random"
+	LB_LEAST       = "least"  //least outstanding requests
+	LB_STICKY      = "sticky" //same endpoint for the same sender
+)
+
+const (
+	EJECT_FAILURES = 5                //consecutive failures to eject an endpoint
+	EJECT_DURATION = 10 * time.Second //first ejection time, doubled on every ejection in a row
+	MAX_EJECT_TIME = 5 * time.Minute
+)
+
+// Endpoint is one node the requests are sent to
+type Endpoint struct {
+	outstanding int64
+	success     uint64
+	failed      uint64
+	Address     string
+	Client      OpClient
+	latency     *Histogram
+	lock        sync.Mutex
+	ejections   uint64
+	failures    int           //consecutive failures
+	backoff     time.Duration //ejection time of the next ejection
+	retryAt     time.Time     //ejected until retryAt
+}
+
+func (self *Endpoint) retryTime() time.Time {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.retryAt
+}
+
+// EndpointPool spread the requests over several nodes, an endpoint failing
+// EJECT_FAILURES times in a row is ejected for a while and retried later
+type EndpointPool struct {
+	strategy  string
+	endpoints []*Endpoint
+	next      uint64
+	lock      sync.Mutex
+	rand      *rand.Rand
+}
+
+// NewEndpointPool return an EndpointPool of the addresses, newClient create the
+// client of an address
+func NewEndpointPool(addresses []string, strategy string, newClient func(address string) OpClient) (*EndpointPool, error) {
+	switch strategy {
+	case LB_ROUND_ROBIN, LB_RANDOM, LB_LEAST, LB_STICKY:
+	default:
+		return nil, fmt.Errorf("unknown load balance strategy %s", strategy)
+	}
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("no endpoint")
+	}
+	self := &EndpointPool{
+		strategy: strategy,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, address := range addresses {
+		self.endpoints = append(self.endpoints, &Endpoint{
+			Address: address,
+			Client:  newClient(address),
+			latency: NewHistogram(),
+			backoff: EJECT_DURATION,
+		})
+	}
+	return self, nil
+}
+
+func (self *EndpointPool) Endpoints() []*Endpoint {
+	return self.endpoints
+}
+
+// Pick return the endpoint to send a request of sender and mark it outstanding,
+// the caller must call Done with the result of the request
+func (self *EndpointPool) Pick(sender *account.Account) *Endpoint {
+	now := time.Now()
+	healthy := make([]*Endpoint, 0, len(self.endpoints))
+	for _, ep := range self.endpoints {
+		if !now.Before(ep.retryTime()) {
+			healthy = append(healthy, ep)
+		}
+	}
+	if len(healthy) == 0 {
+		//every endpoint is ejected, retry the one ejected first
+		healthy = append(healthy, self.endpoints[0])
+		for _, ep := range self.endpoints[1:] {
+			if ep.retryTime().Before(healthy[0].retryTime()) {
+				healthy[0] = ep
+			}
+		}
+	}
+
+	var ep *Endpoint
+	switch self.strategy {
+	case LB_ROUND_ROBIN:
+		ep = healthy[atomic.AddUint64(&self.next, 1)%uint64(len(healthy))]
+	case LB_RANDOM:
+		self.lock.Lock()
+		ep = healthy[self.rand.Intn(len(healthy))]
+		self.lock.Unlock()
+	case LB_LEAST:
+		ep = healthy[0]
+		for _, e := range healthy[1:] {
+			if atomic.LoadInt64(&e.outstanding) < atomic.LoadInt64(&ep.outstanding) {
+				ep = e
+			}
+		}
+	case LB_STICKY:
+		//rendezvous hashing, a sender only move when its endpoint is ejected
+		best := uint64(0)
+		for _, e := range healthy {
+			h := fnv.New64a()
+			h.Write(sender.Address[:])
+			h.Write([]byte(e.Address))
+			if score := h.Sum64(); ep == nil || score > best {
+				ep, best = e, score
+			}
+		}
+	}
+	atomic.AddInt64(&ep.outstanding, 1)
+	return ep
+}
+
+// Done record the result of a request sent to ep, only network errors count
+// to the ejection of the endpoint. The requests of the warmup only count to
+// the ejection
+func (self *EndpointPool) Done(ep *Endpoint, latency time.Duration, err error, warmup bool) {
+	atomic.AddInt64(&ep.outstanding, -1)
+	if !warmup {
+		if err == nil {
+			atomic.AddUint64(&ep.success, 1)
+			ep.latency.Record(latency)
+		} else {
+			atomic.AddUint64(&ep.failed, 1)
+		}
+	}
+
+	ep.lock.Lock()
+	defer ep.lock.Unlock()
+	if err == nil || ErrorClass(err) != ERR_NETWORK {
+		ep.failures = 0
+		ep.backoff = EJECT_DURATION
+		return
+	}
+	ep.failures++
+	if ep.failures < EJECT_FAILURES || time.Now().Before(ep.retryAt) {
+		return
+	}
+	ep.retryAt = time.Now().Add(ep.backoff)
+	fmt.Printf("endpoint %s ejected for %v after %d failures\n", ep.Address, ep.backoff, ep.failures)
+	ep.ejections++
+	ep.failures = 0
+	ep.backoff *= 2
+	if ep.backoff > MAX_EJECT_TIME {
+		ep.backoff = MAX_EJECT_TIME
+	}
+}
+
+func (self *EndpointPool) Print() {
+	fmt.Printf("%-32s %8s %8s %8s %12s %12s %12s\n", "endpoint", "success", "error", "ejected", "p50", "p99", "max")
+	for _, ep := range self.endpoints {
+		ep.lock.Lock()
+		ejections := ep.ejections
+		ep.lock.Unlock()
+		fmt.Printf("%-32s %8d %8d %8d %12v %12v %12v\n", ep.Address, atomic.LoadUint64(&ep.success),
+			atomic.LoadUint64(&ep.failed), ejections, ep.latency.Quantile(0.5), ep.latency.Quantile(0.99),
+			ep.latency.Max())
+	}
+}
+
+func (self *EndpointPool) Summarize(report *Report) {
+	for _, ep := range self.endpoints {
+		ep.lock.Lock()
+		ejections := ep.ejections
+		ep.lock.Unlock()
+		report.Endpoints = append(report.Endpoints, &EndpointSummary{
+			Address:   ep.Address,
+			Success:   atomic.LoadUint64(&ep.success),
+			Error:     atomic.LoadUint64(&ep.failed),
+			Ejections: ejections,
+			Latency:   Summarize(ep.latency),
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package bench
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ontio/ontology/account"
+)
+
+func TestStickyEndpoints(t *testing.T) {
+	pool, err := NewEndpointPool([]string{"a", "b", "c"}, LB_STICKY, func(string) OpClient {
+		return &fakeClient{sent: new(uint64)}
+	})
+	if err != nil {
+		t.Fatalf("NewEndpointPool error:%s", err)
+	}
+	pick := func(sender *account.Account) *Endpoint {
+		ep := pool.Pick(sender)
+		pool.Done(ep, time.Millisecond, nil, false)
+		return ep
+	}
+	senders := newSenders(64).Accounts()
+	picked := make(map[*account.Account]*Endpoint)
+	used := make(map[*Endpoint]int)
+	for _, sender := range senders {
+		picked[sender] = pick(sender)
+		used[picked[sender]]++
+		if ep := pick(sender); ep != picked[sender] {
+			t.Fatalf("sender %x moved from %s to %s", sender.Address[:1], picked[sender].Address, ep.Address)
+		}
+	}
+	if len(used) != 3 {
+		t.Fatalf("%d of 3 endpoints used", len(used))
+	}
+
+	//eject the endpoint of the first sender, only its senders move
+	ejected := picked[senders[0]]
+	for i := 0; i < EJECT_FAILURES; i++ {
+		pool.Done(ejected, time.Millisecond, fmt.Errorf("connection refused"), false)
+	}
+	for _, sender := range senders {
+		ep := pick(sender)
+		if picked[sender] == ejected {
+			if ep == ejected {
+				t.Fatalf("sender %x still on the ejected endpoint %s", sender.Address[:1], ep.Address)
+			}
+		} else if ep != picked[sender] {
+			t.Fatalf("sender %x moved from %s to %s", sender.Address[:1], picked[sender].Address, ep.Address)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package bench
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// error classes of failed requests
+const (
+	ERR_NETWORK      = "network" //network error or timeout
+	ERR_DUPLICATE    = "duplicate"
+	ERR_BALANCE      = "balance" //insufficient balance
+	ERR_TXPOOL_FULL  = "txpoolfull"
+	ERR_VERIFICATION = "verification"
+	ERR_UNKNOWN      = "unknown"
+)
+
+// max distinct sample messages kept for each error class
+const MAX_ERROR_SAMPLES = 3
+
+// errorPatterns map the lower case fragments of the node's error messages to
+// the error class, the first match win
+var errorPatterns = []struct {
+	class     string
+	fragments []string
+}{
+	{ERR_NETWORK, []string{"timeout", "deadline exceeded", "connection refused", "connection reset",
+		"broken pipe", "no such host", "eof", "network is unreachable"}},
+	{ERR_DUPLICATE, []string{"duplicat", "already exist"}},
+	{ERR_TXPOOL_FULL, []string{"pool is full", "pool full", "txpoolfull", "too many"}},
+	{ERR_BALANCE, []string{"insufficient", "not enough", "balance"}},
+	{ERR_VERIFICATION, []string{"verif", "signature", "invalid transaction", "stateless", "stateful"}},
+}
+
+// ErrorClass return the class of a request error
+func ErrorClass(err error) string {
+	if err == context.DeadlineExceeded {
+		return ERR_NETWORK
+	}
+	switch err.(type) {
+	case net.Error, *url.Error:
+		return ERR_NETWORK
+	}
+	msg := strings.ToLower(err.Error())
+	for _, pattern := range errorPatterns {
+		for _, fragment := range pattern.fragments {
+			if strings.Contains(msg, fragment) {
+				return pattern.class
+			}
+		}
+	}
+	return ERR_UNKNOWN
+}
+
+// ErrorStat count the failed requests of every error class and keep a few
+// sample messages of each
+type ErrorStat struct {
+	lock    sync.Mutex
+	counts  map[string]uint64
+	samples map[string][]string
+}
+
+func NewErrorStat() *ErrorStat {
+	return &ErrorStat{
+		counts:  make(map[string]uint64),
+		samples: make(map[string][]string),
+	}
+}
+
+// Add count err, return its class and whether its message is a new sample
+func (self *ErrorStat) Add(err error) (string, bool) {
+	class := ErrorClass(err)
+	msg := err.Error()
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.counts[class]++
+	samples := self.samples[class]
+	if len(samples) >= MAX_ERROR_SAMPLES {
+		return class, false
+	}
+	for _, sample := range samples {
+		if sample == msg {
+			return class, false
+		}
+	}
+	self.samples[class] = append(samples, msg)
+	return class, true
+}
+
+// Classes return the sorted error classes seen
+func (self *ErrorStat) Classes() []string {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	classes := make([]string, 0, len(self.counts))
+	for class := range self.counts {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	return classes
+}
+
+// Class return the count and the sample messages of class
+func (self *ErrorStat) Class(class string) (uint64, []string) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	samples := make([]string, len(self.samples[class]))
+	copy(samples, self.samples[class])
+	return self.counts[class], samples
+}
@@ -0,0 +1,202 @@
+package bench
+
+import (
+	"encoding/json"
+	"math/bits"
+	"sync"
+	"time"
+)
+
+// Histogram is an HDR style log-linear histogram of durations. Values are kept
+// in microseconds, each power of two range is split into HIST_SUB_BUCKETS/2
+// linear buckets so the relative error stays under 1/64.
+const (
+	HIST_SUB_BUCKET_BITS = 7
+	HIST_SUB_BUCKETS     = 1 << HIST_SUB_BUCKET_BITS
+	HIST_MAX_VALUE       = int64(time.Hour / time.Microsecond) //values above are clamped
+)
+
+var histBucketNum = histIndex(HIST_MAX_VALUE) + 1
+
+type Histogram struct {
+	lock   sync.Mutex
+	counts []uint64
+	total  uint64
+	sum    int64
+	min    int64
+	max    int64
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{
+		counts: make([]uint64, histBucketNum),
+		min:    -1,
+	}
+}
+
+// histIndex return the bucket index of v
+func histIndex(v int64) int {
+	if v < HIST_SUB_BUCKETS {
+		return int(v)
+	}
+	shift := bits.Len64(uint64(v)) - HIST_SUB_BUCKET_BITS
+	mant := v >> uint(shift)
+	return HIST_SUB_BUCKETS + (shift-1)*HIST_SUB_BUCKETS/2 + int(mant) - HIST_SUB_BUCKETS/2
+}
+
+// histValue return the highest value which falls into bucket index
+func histValue(index int) int64 {
+	if index < HIST_SUB_BUCKETS {
+		return int64(index)
+	}
+	index -= HIST_SUB_BUCKETS
+	shift := index/(HIST_SUB_BUCKETS/2) + 1
+	mant := int64(index%(HIST_SUB_BUCKETS/2) + HIST_SUB_BUCKETS/2)
+	return (mant+1)<<uint(shift) - 1
+}
+
+func (self *Histogram) Record(d time.Duration) {
+	v := int64(d / time.Microsecond)
+	if v < 0 {
+		v = 0
+	}
+	if v > HIST_MAX_VALUE {
+		v = HIST_MAX_VALUE
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.counts[histIndex(v)]++
+	self.total++
+	self.sum += v
+	if self.min < 0 || v < self.min {
+		self.min = v
+	}
+	if v > self.max {
+		self.max = v
+	}
+}
+
+func (self *Histogram) Count() uint64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.total
+}
+
+func (self *Histogram) Max() time.Duration {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return time.Duration(self.max) * time.Microsecond
+}
+
+func (self *Histogram) Min() time.Duration {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.min < 0 {
+		return 0
+	}
+	return time.Duration(self.min) * time.Microsecond
+}
+
+func (self *Histogram) Mean() time.Duration {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.total == 0 {
+		return 0
+	}
+	return time.Duration(self.sum/int64(self.total)) * time.Microsecond
+}
+
+// Quantile return the value at quantile q, q is in [0, 1]
+func (self *Histogram) Quantile(q float64) time.Duration {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.total == 0 {
+		return 0
+	}
+	if q > 1 {
+		q = 1
+	}
+	rank := uint64(q*float64(self.total) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	seen := uint64(0)
+	for i, c := range self.counts {
+		seen += c
+		if seen >= rank {
+			v := histValue(i)
+			if v > self.max {
+				v = self.max
+			}
+			return time.Duration(v) * time.Microsecond
+		}
+	}
+	return time.Duration(self.max) * time.Microsecond
+}
+
+// Merge add the values of other into the histogram
+func (self *Histogram) Merge(other *Histogram) {
+	other.lock.Lock()
+	counts := make([]uint64, len(other.counts))
+	copy(counts, other.counts)
+	total, sum, min, max := other.total, other.sum, other.min, other.max
+	other.lock.Unlock()
+	if total == 0 {
+		return
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	for i, c := range counts {
+		self.counts[i] += c
+	}
+	self.total += total
+	self.sum += sum
+	if self.min < 0 || min < self.min {
+		self.min = min
+	}
+	if max > self.max {
+		self.max = max
+	}
+}
+
+// histogramData is the sparse json form of a Histogram, only the non empty
+// buckets are kept
+type histogramData struct {
+	Buckets map[int]uint64 `json:"Buckets"`
+	Sum     int64          `json:"Sum"`
+	Min     int64          `json:"Min"`
+	Max     int64          `json:"Max"`
+}
+
+func (self *Histogram) MarshalJSON() ([]byte, error) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	data := &histogramData{Buckets: make(map[int]uint64), Sum: self.sum, Min: self.min, Max: self.max}
+	for i, c := range self.counts {
+		if c > 0 {
+			data.Buckets[i] = c
+		}
+	}
+	return json.Marshal(data)
+}
+
+func (self *Histogram) UnmarshalJSON(b []byte) error {
+	data := &histogramData{}
+	err := json.Unmarshal(b, data)
+	if err != nil {
+		return err
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.counts = make([]uint64, histBucketNum)
+	self.total = 0
+	for i, c := range data.Buckets {
+		if i < 0 || i >= histBucketNum {
+			continue
+		}
+		self.counts[i] = c
+		self.total += c
+	}
+	self.sum, self.min, self.max = data.Sum, data.Min, data.Max
+	return nil
+}
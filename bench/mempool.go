@@ -0,0 +1,248 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ontio/ontology/common"
+)
+
+// recently submitted transactions whose pool state is queried by a sample
+const MEMPOOL_STATE_SAMPLES = 5
+
+// consecutive windows of rising min depth flagged as saturation
+const MEMPOOL_SATURATION_WINDOWS = 3
+
+type MempoolSample struct {
+	Time      time.Time `json:"Time"`
+	Verified  uint32    `json:"Verified"`
+	Verifying uint32    `json:"Verifying"`
+	Depth     uint32    `json:"Depth"`
+	SubmitTPS float64   `json:"SubmitTPS"` //accepted submissions per second since the previous sample
+	DrainTPS  float64   `json:"DrainTPS"`  //submissions minus the depth growth per second
+	Sampled   int       `json:"Sampled"`   //recently submitted transactions whose state was queried
+	InPool    int       `json:"InPool"`    //sampled transactions still in the pool
+	submitted uint64
+}
+
+// Mempool sample the depth of the node's txpool through the json rpc
+// getmempooltxcount and the state of a few recently submitted transactions
+// through getmempooltxstate. The pool is saturated when the min depth of a
+// window rise over consecutive windows, the window should span a few blocks
+// since every block drain the pool
+type Mempool struct {
+	lock      sync.Mutex
+	address   string
+	client    *http.Client
+	window    time.Duration
+	submitted uint64
+	recent    []common.Uint256
+	next      int
+	samples   []*MempoolSample
+	errors    uint64
+}
+
+func NewMempool(address string, window time.Duration) *Mempool {
+	return &Mempool{
+		address: address,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		window:  window,
+		recent:  make([]common.Uint256, 0, MEMPOOL_STATE_SAMPLES),
+	}
+}
+
+// Submit count a transaction accepted by the node
+func (self *Mempool) Submit(hash common.Uint256) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.submitted++
+	if len(self.recent) < MEMPOOL_STATE_SAMPLES {
+		self.recent = append(self.recent, hash)
+		return
+	}
+	self.recent[self.next] = hash
+	self.next = (self.next + 1) % MEMPOOL_STATE_SAMPLES
+}
+
+type mempoolResponse struct {
+	Error  int64           `json:"error"`
+	Desc   string          `json:"desc"`
+	Result json.RawMessage `json:"result"`
+}
+
+// call the json rpc method, return the response whatever its error code
+func (self *Mempool) call(method string, params []interface{}) (*mempoolResponse, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+		"id":      1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := self.client.Post(self.address, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	ret := &mempoolResponse{}
+	err = json.NewDecoder(resp.Body).Decode(ret)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s response error:%s", method, err)
+	}
+	return ret, nil
+}
+
+// Run sample the pool every interval until stopCh is closed
+func (self *Mempool) Run(interval time.Duration, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := self.sample()
+			if err != nil {
+				self.lock.Lock()
+				self.errors++
+				first := self.errors == 1
+				self.lock.Unlock()
+				if first {
+					fmt.Printf("sample mempool error:%s\n", err)
+				}
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+func (self *Mempool) sample() error {
+	resp, err := self.call("getmempooltxcount", []interface{}{})
+	if err != nil {
+		return err
+	}
+	if resp.Error != 0 {
+		return fmt.Errorf("getmempooltxcount error code:%d desc:%s", resp.Error, resp.Desc)
+	}
+	count := make([]uint32, 0, 2)
+	err = json.Unmarshal(resp.Result, &count)
+	if err != nil || len(count) < 2 {
+		return fmt.Errorf("invalid getmempooltxcount result:%s", resp.Result)
+	}
+	s := &MempoolSample{Time: time.Now(), Verified: count[0], Verifying: count[1]}
+	s.Depth = s.Verified + s.Verifying
+
+	self.lock.Lock()
+	recent := make([]common.Uint256, len(self.recent))
+	copy(recent, self.recent)
+	s.submitted = self.submitted
+	self.lock.Unlock()
+	for _, hash := range recent {
+		resp, err := self.call("getmempooltxstate", []interface{}{hash.ToHexString()})
+		if err != nil {
+			return err
+		}
+		s.Sampled++
+		//a transaction out of the pool is unknown to getmempooltxstate
+		if resp.Error == 0 {
+			s.InPool++
+		}
+	}
+
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if n := len(self.samples); n > 0 {
+		prev := self.samples[n-1]
+		seconds := s.Time.Sub(prev.Time).Seconds()
+		s.SubmitTPS = float64(s.submitted-prev.submitted) / seconds
+		s.DrainTPS = s.SubmitTPS - (float64(s.Depth)-float64(prev.Depth))/seconds
+	}
+	self.samples = append(self.samples, s)
+	return nil
+}
+
+// saturation return whether the min depth of the windows rise over
+// MEMPOOL_SATURATION_WINDOWS consecutive windows, and the start of the first
+// of them
+func (self *Mempool) saturation() (bool, time.Time) {
+	if len(self.samples) == 0 || self.window <= 0 {
+		return false, time.Time{}
+	}
+	type window struct {
+		start time.Time
+		min   uint32
+	}
+	windows := make([]*window, 0)
+	for _, s := range self.samples {
+		n := len(windows)
+		if n == 0 || s.Time.Sub(windows[n-1].start) >= self.window {
+			windows = append(windows, &window{start: s.Time, min: s.Depth})
+			continue
+		}
+		if s.Depth < windows[n-1].min {
+			windows[n-1].min = s.Depth
+		}
+	}
+	rising := 1
+	for i := 1; i < len(windows); i++ {
+		if windows[i].min > windows[i-1].min {
+			rising++
+		} else {
+			rising = 1
+		}
+		if rising >= MEMPOOL_SATURATION_WINDOWS {
+			return true, windows[i-rising+1].start
+		}
+	}
+	return false, time.Time{}
+}
+
+// Summarize fill the mempool summary of report
+func (self *Mempool) Summarize(report *Report) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	sum := &MempoolSummary{
+		Window:  self.window.Seconds(),
+		Samples: len(self.samples),
+		Errors:  self.errors,
+		Series:  make([]*MempoolSample, len(self.samples)),
+	}
+	copy(sum.Series, self.samples)
+	total := 0.0
+	for _, s := range self.samples {
+		total += float64(s.Depth)
+		if s.Depth > sum.Peak {
+			sum.Peak = s.Depth
+			sum.PeakAt = s.Time
+		}
+	}
+	if len(self.samples) > 0 {
+		sum.Mean = total / float64(len(self.samples))
+	}
+	sum.Saturated, sum.SaturatedAt = self.saturation()
+	report.Mempool = sum
+}
+
+func (self *Mempool) Print() {
+	report := &Report{}
+	self.Summarize(report)
+	sum := report.Mempool
+	fmt.Printf("mempool samples:%d, errors:%d, mean depth:%.1f, peak:%d\n", sum.Samples, sum.Errors, sum.Mean, sum.Peak)
+	if len(sum.Series) == 0 {
+		return
+	}
+	start := sum.Series[0].Time
+	fmt.Printf("%8s %10s %10s %10s %10s %10s %8s\n", "sec", "verified", "verifying", "depth", "submit", "drain", "inpool")
+	for _, s := range sum.Series {
+		fmt.Printf("%8.1f %10d %10d %10d %10.1f %10.1f %5d/%d\n", s.Time.Sub(start).Seconds(),
+			s.Verified, s.Verifying, s.Depth, s.SubmitTPS, s.DrainTPS, s.InPool, s.Sampled)
+	}
+	if sum.Saturated {
+		fmt.Printf("mempool saturated, the depth keep growing since %.1fs\n", sum.SaturatedAt.Sub(start).Seconds())
+	}
+}
@@ -0,0 +1,63 @@
+package bench
+
+import (
+	"time"
+
+	"github.com/ontio/ontology-stress-test/common/metrics"
+)
+
+// benchMetrics is the prometheus metrics of ont-bench
+type benchMetrics struct {
+	submitted      *metrics.CounterVec
+	succeeded      *metrics.CounterVec
+	failed         *metrics.CounterVec
+	latency        *metrics.HistogramVec
+	confirmed      *metrics.CounterVec
+	confirmLatency *metrics.HistogramVec
+}
+
+var benchMetric *benchMetrics
+
+// EnableMetrics register the bench metrics, it must be called before the run
+func EnableMetrics() {
+	benchMetric = &benchMetrics{
+		submitted: metrics.NewCounterVec("ontbench_submitted_total",
+			"Requests submitted to the node", "kind"),
+		succeeded: metrics.NewCounterVec("ontbench_succeeded_total",
+			"Requests accepted by the node", "kind"),
+		failed: metrics.NewCounterVec("ontbench_failed_total",
+			"Requests failed", "kind", "class"),
+		latency: metrics.NewHistogramVec("ontbench_latency_seconds",
+			"Latency from the intended send time to the response", metrics.DEFAULT_BUCKETS, "kind"),
+		confirmed: metrics.NewCounterVec("ontbench_confirmed_total",
+			"Transactions included in a block"),
+		confirmLatency: metrics.NewHistogramVec("ontbench_confirm_latency_seconds",
+			"Latency from the submission to the block inclusion", metrics.DEFAULT_BUCKETS),
+	}
+}
+
+func metricSubmit(kind string) {
+	if benchMetric != nil {
+		benchMetric.submitted.WithLabelValues(kind).Inc()
+	}
+}
+
+func metricSuccess(kind string, latency time.Duration) {
+	if benchMetric != nil {
+		benchMetric.succeeded.WithLabelValues(kind).Inc()
+		benchMetric.latency.WithLabelValues(kind).Observe(latency.Seconds())
+	}
+}
+
+func metricFail(kind, class string) {
+	if benchMetric != nil {
+		benchMetric.failed.WithLabelValues(kind, class).Inc()
+	}
+}
+
+func metricConfirm(latency time.Duration) {
+	if benchMetric != nil {
+		benchMetric.confirmed.WithLabelValues().Inc()
+		benchMetric.confirmLatency.WithLabelValues().Observe(latency.Seconds())
+	}
+}
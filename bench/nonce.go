@@ -0,0 +1,37 @@
+package bench
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ontio/ontology/common"
+)
+
+// Nonces hand out the transaction nonces of the senders. Every sender count
+// up from a random start, so the transactions of a sender never repeat in a
+// run and hardly collide with the ones of an earlier run
+type Nonces struct {
+	lock sync.Mutex
+	rand *rand.Rand
+	next map[common.Address]uint32
+}
+
+func NewNonces() *Nonces {
+	return &Nonces{
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+		next: make(map[common.Address]uint32),
+	}
+}
+
+// Next return the next nonce of the sender addr
+func (self *Nonces) Next(addr common.Address) uint32 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	nonce, ok := self.next[addr]
+	if !ok {
+		nonce = self.rand.Uint32()
+	}
+	self.next[addr] = nonce + 1
+	return nonce
+}
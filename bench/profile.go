@@ -0,0 +1,279 @@
+package bench
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// load profile shapes
+const (
+	SHAPE_CONST = "const"
+	SHAPE_RAMP  = "ramp"
+	SHAPE_STEP  = "step"
+	SHAPE_SPIKE = "spike"
+	SHAPE_SINE  = "sine"
+)
+
+// ProfileConfig describe one segment of a load profile, the segments of a
+// profile run one after another
+type ProfileConfig struct {
+	Shape     string  `json:"Shape"`     //one of the SHAPE_* shapes
+	Rate      float64 `json:"Rate"`      //const rate, ramp and step start rate, spike baseline, sine mean
+	To        float64 `json:"To"`        //ramp end rate, spike peak rate
+	Step      float64 `json:"Step"`      //rate increase of every step
+	Steps     int     `json:"Steps"`     //step count
+	Amplitude float64 `json:"Amplitude"` //sine amplitude
+	Hold      string  `json:"Hold"`      //hold time of every step
+	Period    string  `json:"Period"`    //spike and sine period
+	Width     string  `json:"Width"`     //spike width
+	Duration  string  `json:"Duration"`  //segment duration, a last const segment without it never ends
+}
+
+// Phase is a part of the profile the report attribute the results to
+type Phase struct {
+	Name     string
+	Start    time.Duration //offset from the start of the run
+	Duration time.Duration //0 means endless
+	Mean     float64       //mean target rate
+	rate     func(t time.Duration) float64
+}
+
+// Rate return the target rate t after the start of the phase
+func (self *Phase) Rate(t time.Duration) float64 {
+	return self.rate(t)
+}
+
+// Profile is the target rate over the run
+type Profile struct {
+	phases   []*Phase
+	duration time.Duration
+}
+
+// ConstProfile return an endless Profile of a constant rate
+func ConstProfile(rate float64) *Profile {
+	return &Profile{phases: []*Phase{constPhase(SHAPE_CONST, rate, 0)}}
+}
+
+func constPhase(name string, rate float64, d time.Duration) *Phase {
+	return &Phase{
+		Name:     name,
+		Duration: d,
+		Mean:     rate,
+		rate:     func(time.Duration) float64 { return rate },
+	}
+}
+
+// ParseProfile parse the command line form of a profile, segments are split
+// by ',' and each segment is one of
+//
+//	const:RATE[:DURATION]
+//	ramp:FROM:TO:DURATION
+//	step:FROM:STEP:HOLD:STEPS
+//	spike:BASE:PEAK:PERIOD:WIDTH:DURATION
+//	sine:MEAN:AMPLITUDE:PERIOD:DURATION
+func ParseProfile(s string) ([]*ProfileConfig, error) {
+	fieldNum := map[string]int{
+		SHAPE_CONST: 2,
+		SHAPE_RAMP:  4,
+		SHAPE_STEP:  5,
+		SHAPE_SPIKE: 6,
+		SHAPE_SINE:  5,
+	}
+	cfgs := make([]*ProfileConfig, 0)
+	for _, seg := range strings.Split(s, ",") {
+		fields := strings.Split(strings.TrimSpace(seg), ":")
+		want, ok := fieldNum[fields[0]]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile shape %s", fields[0])
+		}
+		if len(fields) != want && !(fields[0] == SHAPE_CONST && len(fields) == want+1) {
+			return nil, fmt.Errorf("invalid profile segment %s", seg)
+		}
+		var err error
+		num := func(i int) float64 {
+			v, e := strconv.ParseFloat(fields[i], 64)
+			if e != nil && err == nil {
+				err = fmt.Errorf("invalid number %s in profile segment %s", fields[i], seg)
+			}
+			return v
+		}
+		cfg := &ProfileConfig{Shape: fields[0]}
+		switch cfg.Shape {
+		case SHAPE_CONST:
+			cfg.Rate = num(1)
+			if len(fields) == 3 {
+				cfg.Duration = fields[2]
+			}
+		case SHAPE_RAMP:
+			cfg.Rate, cfg.To, cfg.Duration = num(1), num(2), fields[3]
+		case SHAPE_STEP:
+			cfg.Rate, cfg.Step, cfg.Hold, cfg.Steps = num(1), num(2), fields[3], int(num(4))
+		case SHAPE_SPIKE:
+			cfg.Rate, cfg.To, cfg.Period, cfg.Width, cfg.Duration = num(1), num(2), fields[3], fields[4], fields[5]
+		case SHAPE_SINE:
+			cfg.Rate, cfg.Amplitude, cfg.Period, cfg.Duration = num(1), num(2), fields[3], fields[4]
+		}
+		if err != nil {
+			return nil, err
+		}
+		cfgs = append(cfgs, cfg)
+	}
+	return cfgs, nil
+}
+
+func parseDuration(name, s string, required bool) (time.Duration, error) {
+	if s == "" && !required {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, s)
+	}
+	return d, nil
+}
+
+// NewProfile build the Profile of the segments in cfgs
+func NewProfile(cfgs []*ProfileConfig) (*Profile, error) {
+	if len(cfgs) == 0 {
+		return nil, fmt.Errorf("empty profile")
+	}
+	self := &Profile{}
+	for i, cfg := range cfgs {
+		last := i == len(cfgs)-1
+		d, err := parseDuration("duration", cfg.Duration, !(last && cfg.Shape == SHAPE_CONST) && cfg.Shape != SHAPE_STEP)
+		if err != nil {
+			return nil, err
+		}
+		phases, err := buildPhases(cfg, d)
+		if err != nil {
+			return nil, err
+		}
+		for _, phase := range phases {
+			phase.Start = self.duration
+			self.duration += phase.Duration
+			self.phases = append(self.phases, phase)
+		}
+	}
+	if last := self.phases[len(self.phases)-1]; last.Duration == 0 {
+		//an endless phase without load would never release a task
+		if last.Mean <= 0 {
+			return nil, fmt.Errorf("rate %v of the endless last phase is not positive", last.Mean)
+		}
+		self.duration = 0
+	}
+	return self, nil
+}
+
+func buildPhases(cfg *ProfileConfig, d time.Duration) ([]*Phase, error) {
+	switch cfg.Shape {
+	case SHAPE_CONST:
+		return []*Phase{constPhase(SHAPE_CONST, cfg.Rate, d)}, nil
+	case SHAPE_RAMP:
+		from, to := cfg.Rate, cfg.To
+		return []*Phase{{
+			Name:     SHAPE_RAMP,
+			Duration: d,
+			Mean:     (from + to) / 2,
+			rate: func(t time.Duration) float64 {
+				return from + (to-from)*float64(t)/float64(d)
+			},
+		}}, nil
+	case SHAPE_STEP:
+		hold, err := parseDuration("hold", cfg.Hold, true)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.Steps <= 0 {
+			return nil, fmt.Errorf("invalid steps %d", cfg.Steps)
+		}
+		phases := make([]*Phase, 0, cfg.Steps)
+		for i := 0; i < cfg.Steps; i++ {
+			phases = append(phases, constPhase(fmt.Sprintf("%s-%d", SHAPE_STEP, i+1), cfg.Rate+cfg.Step*float64(i), hold))
+		}
+		return phases, nil
+	case SHAPE_SPIKE:
+		period, err := parseDuration("period", cfg.Period, true)
+		if err != nil {
+			return nil, err
+		}
+		width, err := parseDuration("width", cfg.Width, true)
+		if err != nil {
+			return nil, err
+		}
+		if width >= period {
+			return nil, fmt.Errorf("spike width %v not less than period %v", width, period)
+		}
+		phases := make([]*Phase, 0)
+		for left := d; left > 0; left -= period {
+			base := period - width
+			if base > left {
+				base = left
+			}
+			phases = append(phases, constPhase("base", cfg.Rate, base))
+			if left > base {
+				spike := width
+				if spike > left-base {
+					spike = left - base
+				}
+				phases = append(phases, constPhase(SHAPE_SPIKE, cfg.To, spike))
+			}
+		}
+		return phases, nil
+	case SHAPE_SINE:
+		period, err := parseDuration("period", cfg.Period, true)
+		if err != nil {
+			return nil, err
+		}
+		mean, amplitude := cfg.Rate, cfg.Amplitude
+		return []*Phase{{
+			Name:     SHAPE_SINE,
+			Duration: d,
+			Mean:     mean,
+			rate: func(t time.Duration) float64 {
+				return mean + amplitude*math.Sin(2*math.Pi*float64(t)/float64(period))
+			},
+		}}, nil
+	}
+	return nil, fmt.Errorf("unknown profile shape %s", cfg.Shape)
+}
+
+// At return the index and the phase at elapsed, -1 and nil if the profile is over
+func (self *Profile) At(elapsed time.Duration) (int, *Phase) {
+	i := sort.Search(len(self.phases), func(i int) bool {
+		phase := self.phases[i]
+		return phase.Duration == 0 || phase.Start+phase.Duration > elapsed
+	})
+	if i == len(self.phases) {
+		return -1, nil
+	}
+	return i, self.phases[i]
+}
+
+func (self *Profile) Phases() []*Phase {
+	return self.phases
+}
+
+// Duration return the length of the profile, 0 means endless
+func (self *Profile) Duration() time.Duration {
+	return self.duration
+}
+
+// Scale return the profile of the same phases at share of the target rates
+func (self *Profile) Scale(share float64) *Profile {
+	ret := &Profile{duration: self.duration}
+	for _, phase := range self.phases {
+		rate := phase.rate
+		ret.phases = append(ret.phases, &Phase{
+			Name:     phase.Name,
+			Start:    phase.Start,
+			Duration: phase.Duration,
+			Mean:     phase.Mean * share,
+			rate:     func(t time.Duration) float64 { return rate(t) * share },
+		})
+	}
+	return ret
+}
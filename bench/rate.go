@@ -0,0 +1,199 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// min sleep of the scheduler, tokens accrued meanwhile are released together
+const MIN_SCHEDULE_INTERVAL = time.Millisecond
+
+// Task is one request scheduled by the RateController
+type Task struct {
+	Seq      uint64    //sequence number of the task, start from 0
+	Intended time.Time //time the task should have been sent
+	Phase    int       //index of the profile phase the task belongs to
+}
+
+// RateController is an open-loop token bucket scheduler. Tokens accrue at the
+// target rate of the profile and every token carries the time it became due,
+// so slow workers never slow down the schedule, they only show up as send lag.
+type RateController struct {
+	profile *Profile
+	total   int
+	start   time.Time
+	lag     *LagStat
+}
+
+// NewRateController return a RateController releasing rate tasks per second.
+// total is the number of tasks to release, 0 means unlimited
+func NewRateController(rate, total int) *RateController {
+	return NewProfileController(ConstProfile(float64(rate)), total)
+}
+
+// NewProfileController return a RateController following profile, it stops
+// at the end of the profile or after total tasks, 0 means unlimited
+func NewProfileController(profile *Profile, total int) *RateController {
+	return &RateController{
+		profile: profile,
+		total:   total,
+		lag:     NewLagStat(),
+	}
+}
+
+func (self *RateController) Profile() *Profile {
+	return self.profile
+}
+
+// idle step of the schedule while the target rate is not positive
+const IDLE_STEP = 10 * time.Millisecond
+
+// next return the due offset and the phase of the first token at or after
+// elapsed, phase is -1 if the profile is over or stays without load for ever
+func (self *RateController) next(elapsed time.Duration) (time.Duration, int) {
+	for {
+		i, phase := self.profile.At(elapsed)
+		if phase == nil {
+			return elapsed, -1
+		}
+		rate := phase.Rate(elapsed - phase.Start)
+		if rate > 0 {
+			return elapsed, i
+		}
+		if phase.Duration == 0 {
+			return elapsed, -1
+		}
+		elapsed += IDLE_STEP
+	}
+}
+
+// advance return the due offset and the phase of the token after the one due
+// at elapsed. The rate is integrated in steps of at most IDLE_STEP, so a low
+// rate never jumps past the end of its phase or a later rise of the rate
+func (self *RateController) advance(elapsed time.Duration) (time.Duration, int) {
+	credit := 0.0
+	for {
+		_, phase := self.profile.At(elapsed)
+		if phase == nil {
+			return elapsed, -1
+		}
+		rate := phase.Rate(elapsed - phase.Start)
+		if rate > 0 {
+			gap := time.Duration((1 - credit) * float64(time.Second) / rate)
+			if gap <= IDLE_STEP {
+				return self.next(elapsed + gap)
+			}
+			credit += rate * IDLE_STEP.Seconds()
+		} else if phase.Duration == 0 {
+			return elapsed, -1
+		}
+		elapsed += IDLE_STEP
+	}
+}
+
+// Run releases tasks into taskCh evenly spread at the target rate until total
+// tasks were released, the profile is over or ctx is done. taskCh is closed
+// on return.
+func (self *RateController) Run(ctx context.Context, taskCh chan<- *Task) {
+	defer close(taskCh)
+	self.start = time.Now()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	<-timer.C
+
+	seq := uint64(0)
+	elapsed, phase := self.next(0)
+	for phase >= 0 && (self.total <= 0 || seq < uint64(self.total)) {
+		wait := time.Until(self.start.Add(elapsed))
+		if wait > 0 {
+			if wait < MIN_SCHEDULE_INTERVAL {
+				wait = MIN_SCHEDULE_INTERVAL
+			}
+			timer.Reset(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+		//release every token which is due by now
+		now := time.Now()
+		for phase >= 0 && (self.total <= 0 || seq < uint64(self.total)) {
+			due := self.start.Add(elapsed)
+			if due.After(now) {
+				break
+			}
+			select {
+			case taskCh <- &Task{Seq: seq, Intended: due, Phase: phase}:
+			case <-ctx.Done():
+				return
+			}
+			seq++
+			elapsed, phase = self.advance(elapsed)
+		}
+	}
+}
+
+// Sent records the actual send time of task
+func (self *RateController) Sent(task *Task, at time.Time) {
+	self.lag.Add(at.Sub(task.Intended))
+}
+
+func (self *RateController) Lag() *LagStat {
+	return self.lag
+}
+
+// LagStat collect how far actual sends fall behind the intended send time
+type LagStat struct {
+	lock  sync.Mutex
+	count uint64
+	late  uint64
+	sum   time.Duration
+	max   time.Duration
+}
+
+// a send later than LATE_THRESHOLD is counted as late
+const LATE_THRESHOLD = 10 * time.Millisecond
+
+func NewLagStat() *LagStat {
+	return &LagStat{}
+}
+
+func (self *LagStat) Add(lag time.Duration) {
+	if lag < 0 {
+		lag = 0
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.count++
+	self.sum += lag
+	if lag > self.max {
+		self.max = lag
+	}
+	if lag > LATE_THRESHOLD {
+		self.late++
+	}
+}
+
+func (self *LagStat) String() string {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	mean := time.Duration(0)
+	if self.count > 0 {
+		mean = self.sum / time.Duration(self.count)
+	}
+	return fmt.Sprintf("send lag count:%d, mean:%v, max:%v, late(>%v):%d",
+		self.count, mean, self.max, LATE_THRESHOLD, self.late)
+}
+
+func (self *LagStat) Summarize() *LatencySummary {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	sum := &LatencySummary{Count: self.count, Max: millis(self.max)}
+	if self.count > 0 {
+		sum.Mean = millis(self.sum / time.Duration(self.count))
+	}
+	return sum
+}
@@ -0,0 +1,28 @@
+package bench
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestIdleProfile(t *testing.T) {
+	_, err := NewProfile([]*ProfileConfig{{Shape: SHAPE_CONST, Rate: 100, Duration: "1s"}, {Shape: SHAPE_CONST, Rate: 0}})
+	if err == nil {
+		t.Fatal("endless last phase without load accepted")
+	}
+
+	//a profile without load for ever must end the run instead of spinning
+	for _, rate := range []float64{0, -1} {
+		taskCh := make(chan *Task)
+		go NewProfileController(ConstProfile(rate), 0).Run(context.Background(), taskCh)
+		select {
+		case task, ok := <-taskCh:
+			if ok {
+				t.Fatalf("rate %v released task %d", rate, task.Seq)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("rate %v never ended the run", rate)
+		}
+	}
+}
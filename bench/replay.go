@@ -0,0 +1,132 @@
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/types"
+)
+
+// transactions decoded ahead of the senders
+const REPLAY_BUFFER = 4096
+
+type replayTx struct {
+	tx  *types.Transaction
+	err error
+}
+
+// TxFile stream the pre-signed transactions of a file written by testcli's
+// GenTransferFile, each line is "hash,txhex". Transactions are decoded ahead
+// in the background so the senders only pay for the submission.
+type TxFile struct {
+	name     string
+	count    int
+	txCh     chan *replayTx
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// OpenTxFile count the transactions of the file and start decoding them
+func OpenTxFile(name string) (*TxFile, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	count := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) != "" {
+			count++
+		}
+	}
+	f.Close()
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s error:%s", name, err)
+	}
+
+	f, err = os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	self := &TxFile{
+		name:   name,
+		count:  count,
+		txCh:   make(chan *replayTx, REPLAY_BUFFER),
+		stopCh: make(chan struct{}),
+	}
+	go self.decode(f)
+	return self, nil
+}
+
+func (self *TxFile) decode(f *os.File) {
+	defer f.Close()
+	defer close(self.txCh)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		select {
+		case self.txCh <- decodeTxLine(line, text):
+		case <-self.stopCh:
+			return
+		}
+	}
+}
+
+// Close stop decoding the transactions left in the file
+func (self *TxFile) Close() {
+	self.stopOnce.Do(func() {
+		close(self.stopCh)
+	})
+}
+
+func decodeTxLine(line int, text string) *replayTx {
+	fields := strings.Split(text, ",")
+	if len(fields) != 2 {
+		return &replayTx{err: fmt.Errorf("invalid line %d", line)}
+	}
+	raw, err := hex.DecodeString(fields[1])
+	if err != nil {
+		return &replayTx{err: fmt.Errorf("invalid tx hex at line %d:%s", line, err)}
+	}
+	tx := &types.Transaction{}
+	err = tx.Deserialize(bytes.NewReader(raw))
+	if err != nil {
+		return &replayTx{err: fmt.Errorf("deserialize tx at line %d error:%s", line, err)}
+	}
+	hash, err := common.Uint256FromHexString(fields[0])
+	if err != nil {
+		return &replayTx{err: fmt.Errorf("invalid tx hash at line %d:%s", line, err)}
+	}
+	if hash != tx.Hash() {
+		return &replayTx{err: fmt.Errorf("tx hash %s at line %d mismatch the tx %s", fields[0], line, tx.Hash().ToHexString())}
+	}
+	return &replayTx{tx: tx}
+}
+
+// Count return the number of transactions in the file
+func (self *TxFile) Count() int {
+	return self.count
+}
+
+// Next return the next transaction, io.EOF after the last one
+func (self *TxFile) Next() (*types.Transaction, error) {
+	rtx, ok := <-self.txCh
+	if !ok {
+		return nil, io.EOF
+	}
+	return rtx.tx, rtx.err
+}
@@ -0,0 +1,114 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ontio/ontology/common"
+)
+
+// writeTxFile write the lines "hash,txhex" of the txs with nonces 1 to count
+// like GenTransferFile, hash override the hash column of the last line
+func writeTxFile(t *testing.T, count int, hash string) string {
+	var buf bytes.Buffer
+	for i := 1; i <= count; i++ {
+		tx := newTx(t, uint32(i))
+		var raw bytes.Buffer
+		if err := tx.Serialize(&raw); err != nil {
+			t.Fatalf("Serialize error:%s", err)
+		}
+		txHash := tx.Hash().ToHexString()
+		if i == count && hash != "" {
+			txHash = hash
+		}
+		fmt.Fprintf(&buf, "%s,%s\n", txHash, hex.EncodeToString(raw.Bytes()))
+	}
+	name := filepath.Join(t.TempDir(), "txs.dat")
+	if err := ioutil.WriteFile(name, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("WriteFile error:%s", err)
+	}
+	return name
+}
+
+func TestTxFileHash(t *testing.T) {
+	wrong := common.Uint256{1}
+	txFile, err := OpenTxFile(writeTxFile(t, 3, wrong.ToHexString()))
+	if err != nil {
+		t.Fatalf("OpenTxFile error:%s", err)
+	}
+	defer txFile.Close()
+	for i := uint32(1); i <= 2; i++ {
+		tx, err := txFile.Next()
+		if err != nil {
+			t.Fatalf("Next %d error:%s", i, err)
+		}
+		if tx.Nonce != i {
+			t.Fatalf("nonce of tx %d is %d", i, tx.Nonce)
+		}
+	}
+	if _, err = txFile.Next(); err == nil {
+		t.Fatal("tx of a mismatched hash accepted")
+	}
+	if _, err = txFile.Next(); err != io.EOF {
+		t.Fatalf("Next after the last tx return %v, want EOF", err)
+	}
+}
+
+func TestTxFileClose(t *testing.T) {
+	txFile, err := OpenTxFile(writeTxFile(t, REPLAY_BUFFER+100, ""))
+	if err != nil {
+		t.Fatalf("OpenTxFile error:%s", err)
+	}
+	if _, err = txFile.Next(); err != nil {
+		t.Fatalf("Next error:%s", err)
+	}
+	txFile.Close()
+	txFile.Close()
+	//the decoder blocked on the full buffer close it once stopped
+	done := make(chan int)
+	go func() {
+		n := 0
+		for {
+			if _, err := txFile.Next(); err == io.EOF {
+				done <- n
+				return
+			}
+			n++
+		}
+	}()
+	select {
+	case n := <-done:
+		if n >= REPLAY_BUFFER+99 {
+			t.Fatalf("%d txs decoded after Close", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("decoder not stopped by Close")
+	}
+}
+
+func TestTrackRejected(t *testing.T) {
+	confirmer := NewConfirmer(time.Second)
+	now := time.Now()
+	landed, lost, seen := common.Uint256{1}, common.Uint256{2}, common.Uint256{3}
+	confirmer.TrackRejected(landed, now)
+	confirmer.TrackRejected(lost, now)
+	confirmer.OnBlock(&BlockInfo{Height: 1, Hashes: []common.Uint256{landed, seen}, At: now})
+	//a rejected tx may be reported after it was packed
+	confirmer.TrackRejected(seen, now)
+	if n := confirmer.expire(now.Add(2 * time.Second)); n != 0 {
+		t.Fatalf("%d txs still pending", n)
+	}
+	if n := confirmer.Landed(); n != 2 {
+		t.Fatalf("landed %d, want 2", n)
+	}
+	tracked, confirmed := confirmer.Confirmed()
+	if confirmed != 0 || tracked != 0 || len(confirmer.Missing()) != 0 {
+		t.Fatalf("rejected txs counted, confirmed %d/%d, missing %d", confirmed, tracked, len(confirmer.Missing()))
+	}
+}
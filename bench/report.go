@@ -0,0 +1,225 @@
+package bench
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/ontio/ontology-stress-test/common/report"
+)
+
+// the schema shared with the reports of the other tools
+type (
+	Environment   = report.Environment
+	Summary       = report.Summary
+	SecondSummary = report.SecondSummary
+)
+
+// Report is the machine readable result of a run
+type Report struct {
+	report.Header
+	Interrupted bool                     `json:"Interrupted"`           //stopped by a signal before the end
+	Aborted     string                   `json:"Aborted,omitempty"`     //reason the bench aborted the run
+	Transport   string                   `json:"Transport,omitempty"`   //interface the transactions were submitted through
+	Model       string                   `json:"Model,omitempty"`       //open or closed
+	Concurrency int                      `json:"Concurrency,omitempty"` //workers of the open model, virtual users of the closed one
+	Warmup      *WindowSummary           `json:"Warmup,omitempty"`      //requests of the warmup, excluded from the other results
+	Summary     *Summary                 `json:"Summary"`
+	SendLag     *LatencySummary          `json:"SendLag,omitempty"`
+	Signing     *SignSummary             `json:"Signing,omitempty"` //signing stage of a pipelined run
+	Latency     *LatencySummary          `json:"Latency,omitempty"`
+	Service     *LatencySummary          `json:"Service,omitempty"`
+	Confirm     *ConfirmSummary          `json:"Confirm,omitempty"`
+	Blocks      *BlockSummary            `json:"Blocks,omitempty"` //committed throughput of the followed blocks
+	Mempool     *MempoolSummary          `json:"Mempool,omitempty"`
+	Audit       *AuditSummary            `json:"Audit,omitempty"`
+	Kinds       map[string]*OpSummary    `json:"Kinds,omitempty"`
+	Phases      []*PhaseSummary          `json:"Phases,omitempty"`
+	Endpoints   []*EndpointSummary       `json:"Endpoints,omitempty"`
+	Errors      map[string]*ErrorSummary `json:"Errors,omitempty"` //by error class
+	Series      []*SecondSummary         `json:"Series,omitempty"`
+}
+
+// WindowSummary is the outcome of the requests of a time window
+type WindowSummary struct {
+	Start    time.Time       `json:"Start"`
+	End      time.Time       `json:"End"`
+	Seconds  float64         `json:"Seconds"`
+	Requests uint64          `json:"Requests"`
+	Success  uint64          `json:"Success"`
+	Errors   uint64          `json:"Errors"`
+	TPS      float64         `json:"TPS"`
+	Latency  *LatencySummary `json:"Latency"`
+}
+
+// LatencySummary is the percentiles of a Histogram in milliseconds
+type LatencySummary struct {
+	Count uint64  `json:"Count"`
+	Mean  float64 `json:"Mean"`
+	P50   float64 `json:"P50"`
+	P90   float64 `json:"P90"`
+	P99   float64 `json:"P99"`
+	P999  float64 `json:"P999"`
+	Max   float64 `json:"Max"`
+}
+
+type SignSummary struct {
+	Workers  int             `json:"Workers"`
+	Signed   uint64          `json:"Signed"`
+	Errors   uint64          `json:"Errors"`
+	Seconds  float64         `json:"Seconds"`
+	TPS      float64         `json:"TPS"`      //signed over the whole run, bound by the submission rate
+	Busy     float64         `json:"Busy"`     //seconds the workers spent signing
+	Usage    float64         `json:"Usage"`    //busy ratio of the workers
+	Capacity float64         `json:"Capacity"` //tx/s the workers can sign when always busy
+	Latency  *LatencySummary `json:"Latency"`
+}
+
+type ConfirmSummary struct {
+	Tracked   uint64          `json:"Tracked"`
+	Confirmed uint64          `json:"Confirmed"`
+	Landed    uint64          `json:"Landed"` //included although the submission failed
+	Missing   []string        `json:"Missing"`
+	Latency   *LatencySummary `json:"Latency"`
+}
+
+type BlockSummary struct {
+	GenBlockTime float64         `json:"GenBlockTime"` //seconds
+	Blocks       int             `json:"Blocks"`
+	Committed    uint64          `json:"Committed"`
+	Submitted    uint64          `json:"Submitted"`
+	CommittedTPS float64         `json:"CommittedTPS"`
+	SubmittedTPS float64         `json:"SubmittedTPS"`
+	MeanTxs      float64         `json:"MeanTxs"`
+	MaxTxs       int             `json:"MaxTxs"`
+	EmptyBlocks  int             `json:"EmptyBlocks"`
+	FullBlocks   int             `json:"FullBlocks"` //blocks at MaxTransactionInBlock
+	LateBlocks   int             `json:"LateBlocks"` //interval over BLOCK_LATE_FACTOR times GenBlockTime
+	Interval     *LatencySummary `json:"Interval"`
+	MaxLag       int64           `json:"MaxLag"`
+	FinalLag     int64           `json:"FinalLag"`
+	Limit        string          `json:"Limit"` //what limit the committed throughput
+	Series       []*BlockRow     `json:"Series"`
+}
+
+type MempoolSummary struct {
+	Window      float64          `json:"Window"` //seconds of the windows checked for saturation
+	Samples     int              `json:"Samples"`
+	Errors      uint64           `json:"Errors"`
+	Mean        float64          `json:"Mean"`
+	Peak        uint32           `json:"Peak"`
+	PeakAt      time.Time        `json:"PeakAt"`
+	Saturated   bool             `json:"Saturated"` //the depth kept growing
+	SaturatedAt time.Time        `json:"SaturatedAt"`
+	Series      []*MempoolSample `json:"Series"`
+}
+
+type OpSummary struct {
+	Success uint64          `json:"Success"`
+	Error   uint64          `json:"Error"`
+	Latency *LatencySummary `json:"Latency"`
+}
+
+type PhaseSummary struct {
+	Name    string          `json:"Name"`
+	Start   float64         `json:"Start"` //seconds from the start of the run
+	Seconds float64         `json:"Seconds"`
+	Target  float64         `json:"Target"` //mean target rate
+	TPS     float64         `json:"TPS"`
+	Success uint64          `json:"Success"`
+	Error   uint64          `json:"Error"`
+	Latency *LatencySummary `json:"Latency"`
+}
+
+type EndpointSummary struct {
+	Address   string          `json:"Address"`
+	Success   uint64          `json:"Success"`
+	Error     uint64          `json:"Error"`
+	Ejections uint64          `json:"Ejections"`
+	Latency   *LatencySummary `json:"Latency"`
+}
+
+type ErrorSummary struct {
+	Count   uint64   `json:"Count"`
+	Samples []string `json:"Samples"`
+}
+
+func summarizeSecond(second int, h *Histogram) *SecondSummary {
+	return &SecondSummary{
+		Second: second,
+		Count:  h.Count(),
+		P50:    millis(h.Quantile(0.5)),
+		P90:    millis(h.Quantile(0.9)),
+		P99:    millis(h.Quantile(0.99)),
+		Max:    millis(h.Max()),
+	}
+}
+
+// NewReport return an empty Report of tool with the current environment
+func NewReport(tool, version string) *Report {
+	return &Report{
+		Header:  report.NewHeader(tool, version),
+		Summary: &Summary{},
+	}
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// Summarize return the LatencySummary of h
+func Summarize(h *Histogram) *LatencySummary {
+	return &LatencySummary{
+		Count: h.Count(),
+		Mean:  millis(h.Mean()),
+		P50:   millis(h.Quantile(0.5)),
+		P90:   millis(h.Quantile(0.9)),
+		P99:   millis(h.Quantile(0.99)),
+		P999:  millis(h.Quantile(0.999)),
+		Max:   millis(h.Max()),
+	}
+}
+
+func (self *Report) WriteJSON(file string) error {
+	return report.WriteJSON(file, self)
+}
+
+// WriteCSV write the per second series of the report
+func (self *Report) WriteCSV(file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write([]string{"second", "count", "p50_ms", "p90_ms", "p99_ms", "max_ms"})
+	ms := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', 3, 64)
+	}
+	for _, s := range self.Series {
+		w.Write([]string{strconv.Itoa(s.Second), strconv.FormatUint(s.Count, 10),
+			ms(s.P50), ms(s.P90), ms(s.P99), ms(s.Max)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func LoadReport(file string) (*Report, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{}
+	err = json.Unmarshal(data, report)
+	if err != nil {
+		return nil, fmt.Errorf("Unmarshal report %s error:%s", file, err)
+	}
+	if report.Summary == nil {
+		return nil, fmt.Errorf("report %s has no summary", file)
+	}
+	return report, nil
+}
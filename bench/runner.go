@@ -0,0 +1,220 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+)
+
+// Runner submit the operations of a run with its workers and record the
+// outcome. Rate schedule the tasks of the open model and Loop the ones of the
+// closed model, the optional followers are skipped when nil
+type Runner struct {
+	Workload  *Workload
+	Admin     *account.Account //sender of every operation without Senders
+	Senders   *SenderPool
+	Builder   TxBuilder
+	Nonces    *Nonces
+	Endpoints *EndpointPool
+	Stats     *Stats
+	Rate      *RateController
+	Loop      *ClosedLoop
+	Signer    *Signer //sign ahead of the workers when set
+	Confirmer *Confirmer
+	Analyzer  *Analyzer
+	Mempool   *Mempool
+	Auditor   *Auditor
+	Workers   int
+	Queue     int     //buffered tasks between the rate controller and the workers
+	SignBuf   int     //buffered transactions signed ahead
+	ErrBudget float64 //percent of failed requests aborting the run, 0 for no limit
+
+	lock    sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	aborted string
+}
+
+// Run start the workers, the returned channel is closed once every worker
+// returned. The run stops at the end of the schedule, when ctx is done or
+// when the error budget is exhausted
+func (self *Runner) Run(ctx context.Context) <-chan struct{} {
+	self.ctx, self.cancel = context.WithCancel(ctx)
+	work := self.user
+	if self.Loop == nil {
+		taskCh := make(chan *Task, self.Queue)
+		work = func(id int) {
+			self.work(id, taskCh)
+		}
+		if self.Signer != nil {
+			signedCh := make(chan *Signed, self.SignBuf)
+			go self.Signer.Run(self.ctx, taskCh, signedCh, self.prepare)
+			work = func(id int) {
+				self.submitSigned(id, signedCh)
+			}
+		}
+		go self.Rate.Run(self.ctx, taskCh)
+	}
+	return RunWorkers(self.Workers, work)
+}
+
+// Stopped return the channel closed when the run is stopped before its end
+func (self *Runner) Stopped() <-chan struct{} {
+	return self.ctx.Done()
+}
+
+// Stop stop the run, the queued tasks are dropped
+func (self *Runner) Stop() {
+	self.cancel()
+}
+
+// Aborted return why the run was aborted, empty if it was not
+func (self *Runner) Aborted() string {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.aborted
+}
+
+func (self *Runner) prepare(task *Task) (*Operation, *account.Account) {
+	from := self.Admin
+	if self.Senders != nil {
+		from = self.Senders.Sender(task.Seq)
+	}
+	return self.Workload.Next(), from
+}
+
+func (self *Runner) fail(kind string, task *Task, err error) {
+	class, sample := self.Stats.Fail(kind, task, err)
+	if sample {
+		fmt.Printf("%s error(%s):%s\n", kind, class, err)
+	}
+	if self.ErrBudget > 0 && self.Stats.OverBudget(self.ErrBudget) {
+		self.lock.Lock()
+		defer self.lock.Unlock()
+		if self.aborted == "" && self.ctx.Err() == nil {
+			self.aborted = fmt.Sprintf("error rate over budget %v%%", self.ErrBudget)
+			fmt.Printf("error rate over budget %v%%, abort the run\n", self.ErrBudget)
+			self.cancel()
+		}
+	}
+}
+
+// submit send op of task through send and record the outcome, return whether
+// it succeeded
+func (self *Runner) submit(task *Task, op *Operation, from *account.Account,
+	send func(client OpClient) (common.Uint256, error)) bool {
+	sent := time.Now()
+	warmup := self.Stats.Warmup(task)
+	if self.Rate != nil && !warmup {
+		self.Rate.Sent(task, sent)
+	}
+	self.Stats.Submit(op.Kind)
+	ep := self.Endpoints.Pick(from)
+	hash, err := send(ep.Client)
+	self.Endpoints.Done(ep, time.Since(sent), err, warmup)
+	if err != nil {
+		if self.Confirmer != nil && hash != (common.Uint256{}) {
+			self.Confirmer.TrackRejected(hash, sent)
+		}
+		self.fail(op.Kind, task, err)
+		return false
+	}
+	if self.Confirmer != nil {
+		//the warmup is only followed to exclude the lost ones from the audit
+		if warmup {
+			self.Confirmer.TrackWarmup(hash, sent)
+		} else {
+			self.Confirmer.Track(hash, sent)
+		}
+	}
+	if self.Analyzer != nil {
+		if warmup {
+			self.Analyzer.SubmitWarmup(hash)
+		} else {
+			self.Analyzer.Submit(sent)
+		}
+	}
+	if self.Mempool != nil {
+		self.Mempool.Submit(hash)
+	}
+	if self.Auditor != nil {
+		self.Auditor.Record(hash, op.Effects(from.Address))
+	}
+	self.Stats.Record(op.Kind, task, sent, time.Now())
+	return true
+}
+
+// execute sign op of task in the worker and submit it
+func (self *Runner) execute(task *Task) bool {
+	op, from := self.prepare(task)
+	return self.submit(task, op, from, func(client OpClient) (common.Uint256, error) {
+		return op.Execute(client, self.Builder, from, self.Nonces.Next(from.Address))
+	})
+}
+
+// work submit the tasks of taskCh, every worker only touch its own counters,
+// the nonces make the transactions unique whichever worker send them
+func (self *Runner) work(id int, taskCh <-chan *Task) {
+	success, failed := 0, 0
+	for task := range taskCh {
+		if self.ctx.Err() != nil {
+			//drop the queued tasks of a stopped run
+			continue
+		}
+		if self.execute(task) {
+			success++
+		} else {
+			failed++
+		}
+	}
+	fmt.Printf("worker %d done, success:%d, failed:%d:%v\n", id, success, failed, time.Now())
+}
+
+// submitSigned only submit the transactions signed ahead by the Signer
+func (self *Runner) submitSigned(id int, signedCh <-chan *Signed) {
+	success, failed := 0, 0
+	for signed := range signedCh {
+		if self.ctx.Err() != nil {
+			continue
+		}
+		if signed.Err != nil {
+			self.fail(signed.Op.Kind, signed.Task, signed.Err)
+			failed++
+			continue
+		}
+		ok := self.submit(signed.Task, signed.Op, signed.From, func(client OpClient) (common.Uint256, error) {
+			return Submit(client, signed.Tx)
+		})
+		if ok {
+			success++
+		} else {
+			failed++
+		}
+	}
+	fmt.Printf("worker %d done, success:%d, failed:%d:%v\n", id, success, failed, time.Now())
+}
+
+// user is a virtual user of the closed model, it send its next request only
+// after the response of the previous one
+func (self *Runner) user(id int) {
+	success, failed := 0, 0
+	for {
+		task, ok := self.Loop.Next(self.ctx)
+		if !ok {
+			break
+		}
+		if self.execute(task) {
+			success++
+		} else {
+			failed++
+		}
+		if !self.Loop.Think(self.ctx) {
+			break
+		}
+	}
+	fmt.Printf("user %d done, success:%d, failed:%d:%v\n", id, success, failed, time.Now())
+}
@@ -0,0 +1,42 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// Scenario describe a bench run, it is loaded from a json file
+type Scenario struct {
+	Workload []*OpConfig      `json:"Workload"`
+	Profile  []*ProfileConfig `json:"Profile"`
+	SLO      *SLO             `json:"SLO"` //overridden by the slo flags
+}
+
+// OpConfig describe one kind of operation of the workload mix
+type OpConfig struct {
+	Kind     string        `json:"Kind"`     //one of the OP_* kinds
+	Weight   int           `json:"Weight"`   //relative share of the operation
+	Amount   uint64        `json:"Amount"`   //amount of transfer and withdraw
+	To       string        `json:"To"`       //base58 dest address of transfer, use -to if empty
+	GasLimit uint64        `json:"GasLimit"` //gas limit, DEFAULT_GAS_LIMIT if 0
+	Contract string        `json:"Contract"` //hex address of the invoked contract
+	Params   []interface{} `json:"Params"`   //params of the contract invocation
+	Code     string        `json:"Code"`     //hex avm code of the deployed contract
+}
+
+func LoadScenario(file string) (*Scenario, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("ReadFile error:%s", err)
+	}
+	// Remove the UTF-8 Byte Order Mark
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	scenario := &Scenario{}
+	err = json.Unmarshal(data, scenario)
+	if err != nil {
+		return nil, fmt.Errorf("Unmarshal scenario error:%s", err)
+	}
+	return scenario, nil
+}
@@ -0,0 +1,149 @@
+package bench
+
+import (
+	"fmt"
+	"time"
+
+	sdkcom "github.com/ontio/ontology-go-sdk/common"
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+)
+
+// key types of generated sender accounts
+const (
+	KEY_TYPE_ECDSA   = "ecdsa"
+	KEY_TYPE_SM2     = "sm2"
+	KEY_TYPE_ED25519 = "ed25519"
+)
+
+// gas limit of the funding and sweeping transfers
+const SETUP_GAS_LIMIT = 30000
+
+// blocks to wait before the sweep, so the transactions still pending in the
+// txpool are packed and the balances of the senders are final
+const SWEEP_BLOCKS = 2
+
+// BalanceClient is the part of the rpc client to query balances
+type BalanceClient interface {
+	GetBalance(addr common.Address) (*sdkcom.Balance, error)
+}
+
+// TransferClient is the part of the rpc client the SenderPool need to fund
+// and sweep the senders
+type TransferClient interface {
+	BalanceClient
+	Transfer(gasPrice, gasLimit uint64, asset string, from *account.Account, to common.Address, amount uint64) (common.Uint256, error)
+}
+
+// SenderPool spread the load over many sender accounts so the bench is not
+// limited by the state and signer of a single hot account
+type SenderPool struct {
+	accounts []*account.Account
+}
+
+func NewSenderPool(accounts []*account.Account) *SenderPool {
+	return &SenderPool{accounts: accounts}
+}
+
+func (self *SenderPool) Size() int {
+	return len(self.accounts)
+}
+
+func (self *SenderPool) Accounts() []*account.Account {
+	return self.accounts
+}
+
+// Sender return the account which send the task with seq, tasks are spread
+// round robin over the accounts
+func (self *SenderPool) Sender(seq uint64) *account.Account {
+	return self.accounts[seq%uint64(len(self.accounts))]
+}
+
+// Fund transfer ont and ong from admin to every sender, and wait at most
+// timeout until all the senders hold them. The transfers are expected by
+// auditor when it is not nil
+func (self *SenderPool) Fund(client TransferClient, admin *account.Account, ont, ong uint64, timeout time.Duration, auditor *Auditor) error {
+	for i, acc := range self.accounts {
+		if ont > 0 {
+			_, err := client.Transfer(0, SETUP_GAS_LIMIT, "ont", admin, acc.Address, ont)
+			if err != nil {
+				return fmt.Errorf("fund sender %d ont error:%s", i, err)
+			}
+			if auditor != nil {
+				auditor.Expect(transferEffects(OP_ONT_TRANSFER, admin.Address, acc.Address, ont))
+			}
+		}
+		if ong > 0 {
+			_, err := client.Transfer(0, SETUP_GAS_LIMIT, "ong", admin, acc.Address, ong)
+			if err != nil {
+				return fmt.Errorf("fund sender %d ong error:%s", i, err)
+			}
+			if auditor != nil {
+				auditor.Expect(transferEffects(OP_ONG_TRANSFER, admin.Address, acc.Address, ong))
+			}
+		}
+	}
+	deadline := time.Now().Add(timeout)
+	for i := 0; i < len(self.accounts); {
+		balance, err := client.GetBalance(self.accounts[i].Address)
+		if err == nil && balance.Ont >= ont && balance.Ong >= ong {
+			i++
+			continue
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("sender %d not funded after %v", i, timeout)
+		}
+		<-time.After(time.Second)
+	}
+	return nil
+}
+
+// Settle wait at most timeout until blocks more blocks are produced
+func (self *SenderPool) Settle(client BlockClient, blocks uint32, timeout time.Duration) error {
+	start, err := client.GetBlockCount()
+	if err != nil {
+		return fmt.Errorf("GetBlockCount error:%s", err)
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		count, err := client.GetBlockCount()
+		if err == nil && count >= start+blocks {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("no %d blocks after %v", blocks, timeout)
+		}
+		<-time.After(time.Second)
+	}
+}
+
+// Sweep transfer the ont and ong left in every sender back to admin
+func (self *SenderPool) Sweep(client TransferClient, admin *account.Account) error {
+	failed := 0
+	for i, acc := range self.accounts {
+		balance, err := client.GetBalance(acc.Address)
+		if err != nil {
+			fmt.Printf("sweep sender %d GetBalance error:%s\n", i, err)
+			failed++
+			continue
+		}
+		if balance.Ont > 0 {
+			_, err = client.Transfer(0, SETUP_GAS_LIMIT, "ont", acc, admin.Address, balance.Ont)
+			if err != nil {
+				fmt.Printf("sweep sender %d ont error:%s\n", i, err)
+				failed++
+			}
+		}
+		if balance.Ong > 0 {
+			_, err = client.Transfer(0, SETUP_GAS_LIMIT, "ong", acc, admin.Address, balance.Ong)
+			if err != nil {
+				fmt.Printf("sweep sender %d ong error:%s\n", i, err)
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d sweep failed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/core/types"
+)
+
+// Signed is a task whose transaction is built and signed ahead, ready to be
+// submitted
+type Signed struct {
+	Task *Task
+	Op   *Operation
+	From *account.Account
+	Tx   *types.Transaction
+	Err  error
+}
+
+// Signer is the pipeline stage between the rate controller and the submitting
+// workers, it build and sign the transactions with its own workers so the
+// signing cost is measured apart from the submission
+type Signer struct {
+	signed  uint64
+	failed  uint64
+	busy    int64 //nanoseconds spent signing
+	builder TxBuilder
+	nonces  *Nonces
+	workers int
+	latency *Histogram //time to build and sign a transaction
+	warmup  func(task *Task) bool
+	lock    sync.Mutex
+	start   time.Time
+	end     time.Time
+}
+
+// NewSigner return a Signer of workers, the signing time of the tasks of the
+// warmup is left out of the latency
+func NewSigner(builder TxBuilder, nonces *Nonces, workers int, warmup func(task *Task) bool) *Signer {
+	return &Signer{
+		builder: builder,
+		nonces:  nonces,
+		workers: workers,
+		latency: NewHistogram(),
+		warmup:  warmup,
+	}
+}
+
+// Run sign the tasks of taskCh into signedCh until taskCh is closed, then
+// close signedCh. prepare pick the operation and sender of a task. Tasks
+// received after ctx is done are dropped
+func (self *Signer) Run(ctx context.Context, taskCh <-chan *Task, signedCh chan<- *Signed,
+	prepare func(task *Task) (*Operation, *account.Account)) {
+	self.lock.Lock()
+	self.start = time.Now()
+	self.lock.Unlock()
+	wg := &sync.WaitGroup{}
+	for i := 0; i < self.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range taskCh {
+				if ctx.Err() != nil {
+					continue
+				}
+				op, from := prepare(task)
+				start := time.Now()
+				tx, err := op.Sign(self.builder, from, self.nonces.Next(from.Address))
+				atomic.AddInt64(&self.busy, int64(time.Since(start)))
+				if err != nil {
+					atomic.AddUint64(&self.failed, 1)
+				} else {
+					atomic.AddUint64(&self.signed, 1)
+					if !self.warmup(task) {
+						self.latency.Record(time.Since(start))
+					}
+				}
+				signedCh <- &Signed{Task: task, Op: op, From: from, Tx: tx, Err: err}
+			}
+		}()
+	}
+	wg.Wait()
+	self.lock.Lock()
+	self.end = time.Now()
+	self.lock.Unlock()
+	close(signedCh)
+}
+
+func (self *Signer) elapsed() time.Duration {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	end := self.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return end.Sub(self.start)
+}
+
+// Summarize return the throughput and latency of the signing stage. The
+// workers mostly wait for the rate controller, so the capacity is taken from
+// the time they were busy signing
+func (self *Signer) Summarize() *SignSummary {
+	sum := &SignSummary{
+		Workers: self.workers,
+		Signed:  atomic.LoadUint64(&self.signed),
+		Errors:  atomic.LoadUint64(&self.failed),
+		Seconds: self.elapsed().Seconds(),
+		Busy:    time.Duration(atomic.LoadInt64(&self.busy)).Seconds(),
+		Latency: Summarize(self.latency),
+	}
+	if sum.Seconds > 0 {
+		sum.TPS = float64(sum.Signed) / sum.Seconds
+		sum.Usage = sum.Busy / (sum.Seconds * float64(sum.Workers))
+	}
+	if sum.Busy > 0 {
+		sum.Capacity = float64(sum.Signed) / sum.Busy * float64(sum.Workers)
+	}
+	return sum
+}
+
+func (self *Signer) Print() {
+	sum := self.Summarize()
+	fmt.Printf("signed %d by %d workers in %.1fs, %.1f tx/s, %d errors\n",
+		sum.Signed, sum.Workers, sum.Seconds, sum.TPS, sum.Errors)
+	fmt.Printf("signer busy %.1f%%, capacity %.1f tx/s\n", sum.Usage*100, sum.Capacity)
+	fmt.Printf("sign        %s\n", formatHistogram(self.latency))
+}
@@ -0,0 +1,56 @@
+package bench
+
+import (
+	"fmt"
+)
+
+// exit code of a run violating its SLO, compare exit with 1 on regression and
+// 2 on usage errors
+const EXIT_SLO_VIOLATED = 3
+
+// SLO is the assertions checked against the report at the end of a run, a
+// zero field is not checked
+type SLO struct {
+	MinTPS          float64 `json:"MinTPS"`          //min achieved tps
+	MaxP99          float64 `json:"MaxP99"`          //max p99 latency in milliseconds
+	MaxErrorRate    float64 `json:"MaxErrorRate"`    //max error rate in percent
+	MinConfirmRatio float64 `json:"MinConfirmRatio"` //min confirmed of tracked transactions in percent
+}
+
+func (self *SLO) Empty() bool {
+	return self.MinTPS == 0 && self.MaxP99 == 0 && self.MaxErrorRate == 0 && self.MinConfirmRatio == 0
+}
+
+// Check return the violations of the SLO by report
+func (self *SLO) Check(report *Report) []string {
+	violations := make([]string, 0)
+	s := report.Summary
+	if self.MinTPS > 0 && s.TPS < self.MinTPS {
+		violations = append(violations, fmt.Sprintf("tps %.1f below %.1f", s.TPS, self.MinTPS))
+	}
+	if self.MaxP99 > 0 {
+		if report.Latency == nil || report.Latency.Count == 0 {
+			violations = append(violations, "no latency recorded for the p99")
+		} else if report.Latency.P99 > self.MaxP99 {
+			violations = append(violations, fmt.Sprintf("p99 %.3fms over %.3fms", report.Latency.P99, self.MaxP99))
+		}
+	}
+	if self.MaxErrorRate > 0 {
+		if s.Requests == 0 {
+			violations = append(violations, "no request completed for the error rate")
+		} else if rate := errorRate(s); rate > self.MaxErrorRate {
+			violations = append(violations, fmt.Sprintf("error rate %.2f%% over %.2f%%", rate, self.MaxErrorRate))
+		}
+	}
+	if self.MinConfirmRatio > 0 {
+		if report.Confirm == nil || report.Confirm.Tracked == 0 {
+			violations = append(violations, "no confirmation tracked, run with -confirm")
+		} else {
+			ratio := float64(report.Confirm.Confirmed) * 100 / float64(report.Confirm.Tracked)
+			if ratio < self.MinConfirmRatio {
+				violations = append(violations, fmt.Sprintf("confirmation ratio %.2f%% below %.2f%%", ratio, self.MinConfirmRatio))
+			}
+		}
+	}
+	return violations
+}
@@ -0,0 +1,387 @@
+package bench
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OpStat is the outcome of one kind of operation
+type OpStat struct {
+	Success uint64
+	Error   uint64
+	Latency *Histogram
+}
+
+// Stats collect the timing of every request. Latency is measured from the
+// intended send time of the task so a stalled sender does not hide the wait
+// of the requests queued behind it (coordinated omission), service time is
+// measured from the actual send time. The requests of the warmup are only
+// counted apart so connection setup and cold caches do not skew the results.
+type Stats struct {
+	success uint64
+	failed  uint64
+	lock    sync.Mutex
+	start   time.Time
+	latency *Histogram
+	service *Histogram
+	series  []*Histogram //latency of requests completed in each second
+	kinds   map[string]*OpStat
+	profile *Profile
+	phases  []*OpStat //outcome of the requests of each profile phase
+	errors  *ErrorStat
+	end     time.Time
+
+	warmCount uint64        //tasks of the warmup by count
+	warmTime  time.Duration //warmup by duration from the start
+	warm      *OpStat
+	warmEnd   time.Time //completion of the last warmup request
+	measured  time.Time //start of the measurement window
+}
+
+func NewStats(profile *Profile) *Stats {
+	self := &Stats{
+		start:   time.Now(),
+		latency: NewHistogram(),
+		service: NewHistogram(),
+		kinds:   make(map[string]*OpStat),
+		profile: profile,
+		phases:  make([]*OpStat, len(profile.Phases())),
+		errors:  NewErrorStat(),
+		warm:    &OpStat{Latency: NewHistogram()},
+	}
+	self.measured = self.start
+	for i := range self.phases {
+		self.phases[i] = &OpStat{Latency: NewHistogram()}
+	}
+	return self
+}
+
+// SetWarmup set the warmup to the first count tasks and to the tasks intended
+// in the first d of the run, whichever is longer
+func (self *Stats) SetWarmup(count uint64, d time.Duration) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.warmCount = count
+	self.warmTime = d
+	self.measured = self.start.Add(d)
+	if count > 0 {
+		//opened by the first measured task
+		self.measured = time.Time{}
+	}
+}
+
+// Warmup return whether task belong to the warmup
+func (self *Stats) Warmup(task *Task) bool {
+	return task.Seq < self.warmCount || task.Intended.Before(self.start.Add(self.warmTime))
+}
+
+// warmup count a warmup request completed at done
+func (self *Stats) warmup(done time.Time) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if done.After(self.warmEnd) {
+		self.warmEnd = done
+	}
+}
+
+// measure open the measurement window at the first measured task
+func (self *Stats) measure(task *Task) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.measured.IsZero() {
+		self.measured = task.Intended
+	}
+}
+
+// Record add a kind request sent at sent for task and completed at done
+func (self *Stats) Record(kind string, task *Task, sent, done time.Time) {
+	latency := done.Sub(task.Intended)
+	if self.Warmup(task) {
+		atomic.AddUint64(&self.warm.Success, 1)
+		self.warm.Latency.Record(latency)
+		self.warmup(done)
+		metricSuccess(kind, latency)
+		return
+	}
+	self.measure(task)
+	self.latency.Record(latency)
+	self.service.Record(done.Sub(sent))
+	self.second(done).Record(latency)
+	for _, op := range []*OpStat{self.Kind(kind), self.phases[task.Phase]} {
+		atomic.AddUint64(&op.Success, 1)
+		op.Latency.Record(latency)
+	}
+	atomic.AddUint64(&self.success, 1)
+	metricSuccess(kind, latency)
+}
+
+// Submit mark a kind request is about to be sent
+func (self *Stats) Submit(kind string) {
+	metricSubmit(kind)
+}
+
+// Fail add a kind request of task failed with err, return the error class and
+// whether the error message is a new sample of the class
+func (self *Stats) Fail(kind string, task *Task, err error) (string, bool) {
+	if self.Warmup(task) {
+		atomic.AddUint64(&self.warm.Error, 1)
+		self.warmup(time.Now())
+		class := ErrorClass(err)
+		metricFail(kind, class)
+		return class, false
+	}
+	self.measure(task)
+	atomic.AddUint64(&self.Kind(kind).Error, 1)
+	atomic.AddUint64(&self.phases[task.Phase].Error, 1)
+	atomic.AddUint64(&self.failed, 1)
+	class, sample := self.errors.Add(err)
+	metricFail(kind, class)
+	return class, sample
+}
+
+// min completed requests before the error budget is checked
+const ERROR_BUDGET_MIN_REQUESTS = 100
+
+// OverBudget return whether more than percent of the completed requests failed
+func (self *Stats) OverBudget(percent float64) bool {
+	failed := atomic.LoadUint64(&self.failed)
+	total := failed + atomic.LoadUint64(&self.success)
+	if total < ERROR_BUDGET_MIN_REQUESTS {
+		return false
+	}
+	return float64(failed)*100 > percent*float64(total)
+}
+
+// Finish mark the end of the run
+func (self *Stats) Finish() {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.end = time.Now()
+}
+
+// Kind return the stat of the kind operation
+func (self *Stats) Kind(kind string) *OpStat {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	op, ok := self.kinds[kind]
+	if !ok {
+		op = &OpStat{Latency: NewHistogram()}
+		self.kinds[kind] = op
+	}
+	return op
+}
+
+// Kinds return the sorted kinds of the recorded operations
+func (self *Stats) Kinds() []string {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	kinds := make([]string, 0, len(self.kinds))
+	for kind := range self.kinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// second return the histogram of the second of the measurement window t is in
+func (self *Stats) second(t time.Time) *Histogram {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	sec := int(t.Sub(self.measured) / time.Second)
+	if sec < 0 {
+		sec = 0
+	}
+	for len(self.series) <= sec {
+		self.series = append(self.series, NewHistogram())
+	}
+	return self.series[sec]
+}
+
+func (self *Stats) Latency() *Histogram {
+	return self.latency
+}
+
+func (self *Stats) Service() *Histogram {
+	return self.service
+}
+
+// Series return the per second latency histograms
+func (self *Stats) Series() []*Histogram {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	series := make([]*Histogram, len(self.series))
+	copy(series, self.series)
+	return series
+}
+
+func formatHistogram(h *Histogram) string {
+	return fmt.Sprintf("count:%d, mean:%v, p50:%v, p90:%v, p99:%v, p99.9:%v, max:%v",
+		h.Count(), h.Mean(), h.Quantile(0.5), h.Quantile(0.9), h.Quantile(0.99),
+		h.Quantile(0.999), h.Max())
+}
+
+func (self *Stats) Print() {
+	start, end, warmEnd := self.window()
+	if !warmEnd.IsZero() {
+		fmt.Printf("warmup      %.1fs, success:%d, errors:%d, excluded from the statistics\n",
+			warmEnd.Sub(self.start).Seconds(), atomic.LoadUint64(&self.warm.Success), atomic.LoadUint64(&self.warm.Error))
+		fmt.Printf("measurement %.1fs\n", end.Sub(start).Seconds())
+	}
+	fmt.Printf("latency     %s\n", formatHistogram(self.latency))
+	fmt.Printf("service     %s\n", formatHistogram(self.service))
+	fmt.Printf("%-12s %8s %8s %12s %12s %12s\n", "kind", "success", "error", "p50", "p99", "max")
+	for _, kind := range self.Kinds() {
+		op := self.Kind(kind)
+		fmt.Printf("%-12s %8d %8d %12v %12v %12v\n", kind, atomic.LoadUint64(&op.Success),
+			atomic.LoadUint64(&op.Error), op.Latency.Quantile(0.5), op.Latency.Quantile(0.99), op.Latency.Max())
+	}
+	if len(self.phases) > 1 {
+		self.printPhases()
+	}
+	for _, class := range self.errors.Classes() {
+		count, samples := self.errors.Class(class)
+		fmt.Printf("error %-12s %8d\n", class, count)
+		for _, sample := range samples {
+			fmt.Printf("    %s\n", sample)
+		}
+	}
+	fmt.Println("latency per second:")
+	fmt.Printf("%6s %8s %12s %12s %12s %12s\n", "sec", "count", "p50", "p90", "p99", "max")
+	for i, h := range self.Series() {
+		fmt.Printf("%6d %8d %12v %12v %12v %12v\n", i, h.Count(),
+			h.Quantile(0.5), h.Quantile(0.9), h.Quantile(0.99), h.Max())
+	}
+}
+
+// window return the start and the end of the measurement window, and the end
+// of the warmup which is zero if there is no warmup
+func (self *Stats) window() (time.Time, time.Time, time.Time) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	start, end := self.measured, self.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if start.IsZero() || start.After(end) {
+		//the run ended within the warmup
+		start = end
+	}
+	if self.warmCount == 0 && self.warmTime == 0 {
+		return start, end, time.Time{}
+	}
+	warmEnd := self.warmEnd
+	if warmEnd.Before(start) {
+		warmEnd = start
+	}
+	return start, end, warmEnd
+}
+
+// phaseSeconds return how long phase overlap the measurement window from
+// from to to, both relative to the start of the run
+func phaseSeconds(phase *Phase, from, to time.Duration) time.Duration {
+	start, end := phase.Start, to
+	if phase.Duration > 0 && phase.Start+phase.Duration < to {
+		end = phase.Start + phase.Duration
+	}
+	if from > start {
+		start = from
+	}
+	return end - start
+}
+
+func (self *Stats) printPhases() {
+	start, end, _ := self.window()
+	from, to := start.Sub(self.start), end.Sub(self.start)
+	fmt.Printf("%6s %-10s %10s %10s %10s %8s %8s %12s %12s\n",
+		"phase", "name", "start", "target", "tps", "success", "error", "p50", "p99")
+	for i, phase := range self.profile.Phases() {
+		d := phaseSeconds(phase, from, to)
+		if phase.Start >= to {
+			break
+		}
+		if d <= 0 {
+			continue
+		}
+		op := self.phases[i]
+		success := atomic.LoadUint64(&op.Success)
+		fmt.Printf("%6d %-10s %10v %10.1f %10.1f %8d %8d %12v %12v\n", i, phase.Name, phase.Start,
+			phase.Mean, float64(success)/d.Seconds(), success, atomic.LoadUint64(&op.Error),
+			op.Latency.Quantile(0.5), op.Latency.Quantile(0.99))
+	}
+}
+
+// Summarize fill the summary, latency, kinds, phases and series of report
+func (self *Stats) Summarize(report *Report) {
+	start, end, warmEnd := self.window()
+	report.Start, report.End = start, end
+	if !warmEnd.IsZero() {
+		report.Warmup = &WindowSummary{
+			Start:   self.start,
+			End:     warmEnd,
+			Seconds: warmEnd.Sub(self.start).Seconds(),
+			Success: atomic.LoadUint64(&self.warm.Success),
+			Errors:  atomic.LoadUint64(&self.warm.Error),
+			Latency: Summarize(self.warm.Latency),
+		}
+		report.Warmup.Requests = report.Warmup.Success + report.Warmup.Errors
+		if report.Warmup.Seconds > 0 {
+			report.Warmup.TPS = float64(report.Warmup.Success) / report.Warmup.Seconds
+		}
+	}
+	report.Latency = Summarize(self.latency)
+	report.Service = Summarize(self.service)
+
+	summary := report.Summary
+	summary.Seconds = end.Sub(start).Seconds()
+	report.Kinds = make(map[string]*OpSummary)
+	for _, kind := range self.Kinds() {
+		op := self.Kind(kind)
+		sum := &OpSummary{
+			Success: atomic.LoadUint64(&op.Success),
+			Error:   atomic.LoadUint64(&op.Error),
+			Latency: Summarize(op.Latency),
+		}
+		report.Kinds[kind] = sum
+		summary.Success += sum.Success
+		summary.Errors += sum.Error
+	}
+	summary.Requests = summary.Success + summary.Errors
+	report.Errors = make(map[string]*ErrorSummary)
+	for _, class := range self.errors.Classes() {
+		count, samples := self.errors.Class(class)
+		report.Errors[class] = &ErrorSummary{Count: count, Samples: samples}
+	}
+	if summary.Seconds > 0 {
+		summary.TPS = float64(summary.Success) / summary.Seconds
+	}
+
+	from, to := start.Sub(self.start), end.Sub(self.start)
+	for i, phase := range self.profile.Phases() {
+		d := phaseSeconds(phase, from, to)
+		if phase.Start >= to {
+			break
+		}
+		if d <= 0 {
+			continue
+		}
+		op := self.phases[i]
+		sum := &PhaseSummary{
+			Name:    phase.Name,
+			Start:   phase.Start.Seconds(),
+			Seconds: d.Seconds(),
+			Target:  phase.Mean,
+			Success: atomic.LoadUint64(&op.Success),
+			Error:   atomic.LoadUint64(&op.Error),
+			Latency: Summarize(op.Latency),
+		}
+		sum.TPS = float64(sum.Success) / sum.Seconds
+		report.Phases = append(report.Phases, sum)
+	}
+
+	for i, h := range self.Series() {
+		report.Series = append(report.Series, summarizeSecond(i, h))
+	}
+}
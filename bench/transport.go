@@ -0,0 +1,225 @@
+package bench
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/types"
+)
+
+// transports submitting the transactions to the node
+const (
+	TRANSPORT_RPC  = "rpc"
+	TRANSPORT_REST = "rest"
+	TRANSPORT_WS   = "ws"
+)
+
+// timeout of a raw transaction submission
+const SUBMIT_TIMEOUT = 30 * time.Second
+
+func txHex(tx *types.Transaction) (string, error) {
+	buf := new(bytes.Buffer)
+	err := tx.Serialize(buf)
+	if err != nil {
+		return "", fmt.Errorf("serialize tx error:%s", err)
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+// nodeResponse is the response of the node's restful and websocket servers
+type nodeResponse struct {
+	Action string
+	Desc   string
+	Error  int64
+	Result interface{}
+	Id     interface{}
+}
+
+// hash return the tx hash in the result of a sendrawtransaction response
+func (self *nodeResponse) hash() (common.Uint256, error) {
+	if self.Error != 0 {
+		return common.Uint256{}, fmt.Errorf("%s error code:%d desc:%s result:%v", self.Action, self.Error, self.Desc, self.Result)
+	}
+	s, ok := self.Result.(string)
+	if !ok {
+		return common.Uint256{}, fmt.Errorf("invalid %s result:%v", self.Action, self.Result)
+	}
+	return common.Uint256FromHexString(s)
+}
+
+// RestClient submit transactions to the node's restful server
+type RestClient struct {
+	address string
+	client  *http.Client
+}
+
+func NewRestClient(address string) *RestClient {
+	return &RestClient{
+		address: strings.TrimRight(address, "/"),
+		client: &http.Client{
+			Timeout: SUBMIT_TIMEOUT,
+			Transport: &http.Transport{
+				MaxIdleConnsPerHost: 1024,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+	}
+}
+
+func (self *RestClient) SendRawTransaction(tx *types.Transaction) (common.Uint256, error) {
+	data, err := txHex(tx)
+	if err != nil {
+		return common.Uint256{}, err
+	}
+	body, err := json.Marshal(map[string]string{
+		"Action":  "sendrawtransaction",
+		"Version": "1.0.0",
+		"Data":    data,
+	})
+	if err != nil {
+		return common.Uint256{}, err
+	}
+	resp, err := self.client.Post(self.address+"/api/v1/transaction", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return common.Uint256{}, err
+	}
+	defer resp.Body.Close()
+	ret := &nodeResponse{}
+	err = json.NewDecoder(resp.Body).Decode(ret)
+	if err != nil {
+		return common.Uint256{}, fmt.Errorf("decode rest response error:%s", err)
+	}
+	return ret.hash()
+}
+
+// wsConn is a websocket connection matching the responses to the requests
+// by id
+type wsConn struct {
+	lock    sync.Mutex
+	conn    *websocket.Conn
+	pending map[uint64]chan *nodeResponse
+	err     error
+}
+
+// WsClient submit transactions over a few websocket connections to the
+// node's websocket server
+type WsClient struct {
+	address string
+	next    uint64
+	conns   []*wsConn
+}
+
+// NewWsClient dial conns websocket connections to address
+func NewWsClient(address string, conns int) (*WsClient, error) {
+	if conns <= 0 {
+		conns = 1
+	}
+	self := &WsClient{address: address}
+	for i := 0; i < conns; i++ {
+		conn, _, err := websocket.DefaultDialer.Dial(address, nil)
+		if err != nil {
+			self.Close()
+			return nil, fmt.Errorf("websocket dial %s error:%s", address, err)
+		}
+		c := &wsConn{conn: conn, pending: make(map[uint64]chan *nodeResponse)}
+		self.conns = append(self.conns, c)
+		go c.read()
+	}
+	return self, nil
+}
+
+func (self *wsConn) read() {
+	for {
+		resp := &nodeResponse{}
+		err := self.conn.ReadJSON(resp)
+		if err != nil {
+			self.lock.Lock()
+			self.err = err
+			for id, ch := range self.pending {
+				close(ch)
+				delete(self.pending, id)
+			}
+			self.lock.Unlock()
+			return
+		}
+		//pushes of subscriptions have no id
+		id, ok := resp.Id.(float64)
+		if !ok {
+			continue
+		}
+		self.lock.Lock()
+		ch, ok := self.pending[uint64(id)]
+		delete(self.pending, uint64(id))
+		self.lock.Unlock()
+		if ok {
+			ch <- resp
+		}
+	}
+}
+
+func (self *wsConn) request(id uint64, req map[string]interface{}) (*nodeResponse, error) {
+	ch := make(chan *nodeResponse, 1)
+	self.lock.Lock()
+	if self.err != nil {
+		self.lock.Unlock()
+		return nil, fmt.Errorf("websocket closed:%s", self.err)
+	}
+	self.pending[id] = ch
+	err := self.conn.WriteJSON(req)
+	if err != nil {
+		delete(self.pending, id)
+		self.lock.Unlock()
+		return nil, err
+	}
+	self.lock.Unlock()
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			self.lock.Lock()
+			err = self.err
+			self.lock.Unlock()
+			return nil, fmt.Errorf("websocket closed:%s", err)
+		}
+		return resp, nil
+	case <-time.After(SUBMIT_TIMEOUT):
+		self.lock.Lock()
+		delete(self.pending, id)
+		self.lock.Unlock()
+		return nil, fmt.Errorf("websocket request timeout")
+	}
+}
+
+func (self *WsClient) SendRawTransaction(tx *types.Transaction) (common.Uint256, error) {
+	data, err := txHex(tx)
+	if err != nil {
+		return common.Uint256{}, err
+	}
+	id := atomic.AddUint64(&self.next, 1)
+	conn := self.conns[id%uint64(len(self.conns))]
+	resp, err := conn.request(id, map[string]interface{}{
+		"Action":  "sendrawtransaction",
+		"Version": "1.0.0",
+		"Id":      id,
+		"Data":    data,
+	})
+	if err != nil {
+		return common.Uint256{}, err
+	}
+	return resp.hash()
+}
+
+// Close close the websocket connections
+func (self *WsClient) Close() {
+	for _, c := range self.conns {
+		c.conn.Close()
+	}
+}
@@ -0,0 +1,97 @@
+package bench
+
+import (
+	"testing"
+
+	sdk "github.com/ontio/ontology-go-sdk"
+	"github.com/ontio/ontology-stress-test/mock"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/types"
+)
+
+// startNode start a mock node on any free loopback ports
+func startNode(t *testing.T, cfg *mock.Config) *mock.Node {
+	cfg.RpcAddress, cfg.RestAddress, cfg.WsAddress = "127.0.0.1:0", "127.0.0.1:0", "127.0.0.1:0"
+	node := mock.NewNode(cfg)
+	if err := node.Start(); err != nil {
+		t.Fatalf("Start error:%s", err)
+	}
+	return node
+}
+
+func newTx(t *testing.T, nonce uint32) *types.Transaction {
+	tx, err := sdk.NewOntologySdk().Rpc.NewTransferTransaction(0, DEFAULT_GAS_LIMIT, "ont", common.Address{}, common.Address{}, 1)
+	if err != nil {
+		t.Fatalf("NewTransferTransaction error:%s", err)
+	}
+	tx.Nonce = nonce
+	return tx
+}
+
+func testSend(t *testing.T, send func(tx *types.Transaction) (common.Uint256, error)) {
+	for i := uint32(1); i <= 10; i++ {
+		tx := newTx(t, i)
+		hash, err := send(tx)
+		if err != nil {
+			t.Fatalf("SendRawTransaction %d error:%s", i, err)
+		}
+		if hash != tx.Hash() {
+			t.Fatalf("hash of tx %d is %s, want %s", i, hash.ToHexString(), tx.Hash().ToHexString())
+		}
+	}
+	_, err := send(newTx(t, 1))
+	if err == nil {
+		t.Fatal("duplicated transaction accepted")
+	}
+	if class := ErrorClass(err); class != ERR_DUPLICATE {
+		t.Fatalf("class of %s is %s, want %s", err, class, ERR_DUPLICATE)
+	}
+}
+
+func TestRestClient(t *testing.T) {
+	cfg := mock.DefaultConfig()
+	node := startNode(t, cfg)
+	defer node.Stop()
+	testSend(t, NewRestClient("http://"+node.RestAddress()).SendRawTransaction)
+}
+
+func TestWsClient(t *testing.T) {
+	cfg := mock.DefaultConfig()
+	node := startNode(t, cfg)
+	defer node.Stop()
+	client, err := NewWsClient("ws://"+node.WsAddress(), 2)
+	if err != nil {
+		t.Fatalf("NewWsClient error:%s", err)
+	}
+	defer client.Close()
+	testSend(t, client.SendRawTransaction)
+}
+
+func TestErrorInjection(t *testing.T) {
+	cases := []struct {
+		errorRate float64
+		errorDesc string
+		dropRate  float64
+		class     string
+	}{
+		{100, "txpool is full", 0, ERR_TXPOOL_FULL},
+		{100, "insufficient balance", 0, ERR_BALANCE},
+		{100, "signature verification failed", 0, ERR_VERIFICATION},
+		{0, "", 100, ERR_NETWORK},
+	}
+	for _, c := range cases {
+		cfg := mock.DefaultConfig()
+		cfg.ErrorRate = c.errorRate
+		cfg.ErrorDesc = c.errorDesc
+		cfg.DropRate = c.dropRate
+		node := startNode(t, cfg)
+		_, err := NewRestClient("http://" + node.RestAddress()).SendRawTransaction(newTx(t, 1))
+		node.Stop()
+		if err == nil {
+			t.Fatalf("transaction accepted with error %q and drop rate %v", c.errorDesc, c.dropRate)
+		}
+		if class := ErrorClass(err); class != c.class {
+			t.Fatalf("class of %s is %s, want %s", err, class, c.class)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package bench
+
+import "sync"
+
+// RunWorkers start n workers running work with their id from 0, the returned
+// channel is closed after every worker returned
+func RunWorkers(n int, work func(id int)) <-chan struct{} {
+	doneCh := make(chan struct{})
+	wg := &sync.WaitGroup{}
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(id int) {
+			defer wg.Done()
+			work(id)
+		}(i)
+	}
+	go func() {
+		wg.Wait()
+		close(doneCh)
+	}()
+	return doneCh
+}
@@ -0,0 +1,228 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/types"
+	vmtypes "github.com/ontio/ontology/vm/types"
+)
+
+// fakeBuilder build empty transactions and record the nonces every sender
+// signed
+type fakeBuilder struct {
+	lock   sync.Mutex
+	nonces map[common.Address]map[uint32]int
+}
+
+func newFakeBuilder() *fakeBuilder {
+	return &fakeBuilder{nonces: make(map[common.Address]map[uint32]int)}
+}
+
+func (self *fakeBuilder) NewTransferTransaction(gasPrice, gasLimit uint64, asset string, from, to common.Address, amount uint64) (*types.Transaction, error) {
+	return &types.Transaction{GasPrice: gasPrice, GasLimit: gasLimit}, nil
+}
+
+func (self *fakeBuilder) NewWithdrawONGTransaction(gasPrice, gasLimit uint64, address common.Address, amount uint64) (*types.Transaction, error) {
+	return &types.Transaction{GasPrice: gasPrice, GasLimit: gasLimit}, nil
+}
+
+func (self *fakeBuilder) NewNeoVMSInvokeTransaction(gasPrice, gasLimit uint64, smartcodeAddress common.Address, params []interface{}) (*types.Transaction, error) {
+	return &types.Transaction{GasPrice: gasPrice, GasLimit: gasLimit}, nil
+}
+
+func (self *fakeBuilder) NewDeployCodeTransaction(gasPrice, gasLimit uint64, vmType vmtypes.VmType, code []byte, needStorage bool, cname, cversion, cauthor, cemail, cdesc string) *types.Transaction {
+	return &types.Transaction{GasPrice: gasPrice, GasLimit: gasLimit}
+}
+
+func (self *fakeBuilder) SignToTransaction(tx *types.Transaction, signer *account.Account) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	nonces, ok := self.nonces[signer.Address]
+	if !ok {
+		nonces = make(map[uint32]int)
+		self.nonces[signer.Address] = nonces
+	}
+	nonces[tx.Nonce]++
+	return nil
+}
+
+// fakeClient accept the transactions, every failEvery-th one is rejected
+type fakeClient struct {
+	sent      *uint64
+	failEvery uint64
+}
+
+func (self *fakeClient) SendRawTransaction(tx *types.Transaction) (common.Uint256, error) {
+	n := atomic.AddUint64(self.sent, 1)
+	if self.failEvery > 0 && n%self.failEvery == 0 {
+		return common.Uint256{}, fmt.Errorf("txpool is full")
+	}
+	var hash common.Uint256
+	hash[0], hash[1], hash[2], hash[3] = byte(n>>24), byte(n>>16), byte(n>>8), byte(n)
+	return hash, nil
+}
+
+func newSenders(n int) *SenderPool {
+	accounts := make([]*account.Account, 0, n)
+	for i := 0; i < n; i++ {
+		accounts = append(accounts, &account.Account{Address: common.Address{byte(i + 1)}})
+	}
+	return NewSenderPool(accounts)
+}
+
+// newRunner return a Runner of total ont transfers from 4 senders through 3
+// endpoints whose clients reject every failEvery-th transaction
+func newRunner(t *testing.T, total, failEvery int, sent *uint64) *Runner {
+	pool, err := NewEndpointPool([]string{"a", "b", "c"}, LB_ROUND_ROBIN, func(string) OpClient {
+		return &fakeClient{sent: sent, failEvery: uint64(failEvery)}
+	})
+	if err != nil {
+		t.Fatalf("NewEndpointPool error:%s", err)
+	}
+	rate := NewRateController(6000, total)
+	return &Runner{
+		Workload:  DefaultWorkload(common.Address{}),
+		Senders:   newSenders(4),
+		Builder:   newFakeBuilder(),
+		Nonces:    NewNonces(),
+		Endpoints: pool,
+		Stats:     NewStats(rate.Profile()),
+		Rate:      rate,
+		Confirmer: NewConfirmer(time.Minute),
+		Workers:   8,
+		Queue:     16,
+		SignBuf:   16,
+	}
+}
+
+func wait(t *testing.T, doneCh <-chan struct{}) {
+	select {
+	case <-doneCh:
+	case <-time.After(10 * time.Second):
+		t.Fatal("workers not done")
+	}
+}
+
+func TestRunner(t *testing.T) {
+	const total, warmup, failEvery = 600, 60, 10
+	for _, signers := range []int{0, 2} {
+		sent := uint64(0)
+		runner := newRunner(t, total, failEvery, &sent)
+		runner.Stats.SetWarmup(warmup, 0)
+		builder := runner.Builder.(*fakeBuilder)
+		if signers > 0 {
+			runner.Signer = NewSigner(builder, runner.Nonces, signers, runner.Stats.Warmup)
+		}
+		wait(t, runner.Run(context.Background()))
+		runner.Stop()
+		stats := runner.Stats
+		stats.Finish()
+
+		if sent != total {
+			t.Fatalf("signers %d: sent %d, want %d", signers, sent, total)
+		}
+		if runner.Aborted() != "" {
+			t.Fatalf("signers %d: run aborted:%s", signers, runner.Aborted())
+		}
+		if n := runner.Rate.Lag().Summarize().Count; n != total-warmup {
+			t.Fatalf("signers %d: send lag count %d, want %d", signers, n, total-warmup)
+		}
+		warm := stats.warm.Success + stats.warm.Error
+		if warm != warmup {
+			t.Fatalf("signers %d: warmup requests %d, want %d", signers, warm, warmup)
+		}
+		if stats.success+stats.failed != total-warmup {
+			t.Fatalf("signers %d: measured requests %d, want %d", signers, stats.success+stats.failed, total-warmup)
+		}
+		if count, _ := stats.errors.Class(ERR_TXPOOL_FULL); count != stats.failed {
+			t.Fatalf("signers %d: txpoolfull errors %d, want %d", signers, count, stats.failed)
+		}
+		op := stats.Kind(OP_ONT_TRANSFER)
+		if op.Success != stats.success || op.Error != stats.failed || op.Latency.Count() != stats.success {
+			t.Fatalf("signers %d: kind success %d error %d latency %d, want %d %d", signers, op.Success, op.Error,
+				op.Latency.Count(), stats.success, stats.failed)
+		}
+		tracked, _ := runner.Confirmer.Confirmed()
+		if tracked != stats.success {
+			t.Fatalf("signers %d: confirmer tracked %d, want %d", signers, tracked, stats.success)
+		}
+		if signers > 0 {
+			sum := runner.Signer.Summarize()
+			if sum.Signed != total || sum.Latency.Count != total-warmup {
+				t.Fatalf("signers %d: signed %d latency %d, want %d %d", signers, sum.Signed,
+					sum.Latency.Count, total, total-warmup)
+			}
+			//the signers wait for the schedule most of the run
+			if sum.Busy <= 0 || sum.Usage > 1 || sum.Capacity < sum.TPS {
+				t.Fatalf("signers %d: busy %vs usage %v capacity %v under tps %v", signers, sum.Busy,
+					sum.Usage, sum.Capacity, sum.TPS)
+			}
+		}
+
+		success, failed := uint64(0), uint64(0)
+		for _, ep := range runner.Endpoints.Endpoints() {
+			if ep.outstanding != 0 {
+				t.Fatalf("signers %d: endpoint %s has %d outstanding requests", signers, ep.Address, ep.outstanding)
+			}
+			success += ep.success
+			failed += ep.failed
+		}
+		if success+failed != total-warmup {
+			t.Fatalf("signers %d: endpoints measured %d requests, want %d", signers, success+failed, total-warmup)
+		}
+
+		//the nonces keep the transactions unique whichever worker sign them
+		signed := 0
+		for addr, nonces := range builder.nonces {
+			for nonce, n := range nonces {
+				if n != 1 {
+					t.Fatalf("signers %d: nonce %d of sender %x signed %d times", signers, nonce, addr[:1], n)
+				}
+			}
+			if len(nonces) != total/runner.Senders.Size() {
+				t.Fatalf("signers %d: sender %x signed %d transactions, want %d", signers, addr[:1],
+					len(nonces), total/runner.Senders.Size())
+			}
+			signed += len(nonces)
+		}
+		if signed != total {
+			t.Fatalf("signers %d: signed %d transactions, want %d", signers, signed, total)
+		}
+	}
+}
+
+func TestRunnerClosedLoop(t *testing.T) {
+	const total = 200
+	sent := uint64(0)
+	runner := newRunner(t, total, 0, &sent)
+	runner.Rate = nil
+	runner.Loop = NewClosedLoop(total, 0)
+	wait(t, runner.Run(context.Background()))
+	runner.Stop()
+	if sent != total || runner.Stats.success != total {
+		t.Fatalf("sent %d success %d, want %d", sent, runner.Stats.success, total)
+	}
+}
+
+func TestRunnerErrorBudget(t *testing.T) {
+	sent := uint64(0)
+	runner := newRunner(t, 0, 2, &sent)
+	runner.ErrBudget = 20
+	doneCh := runner.Run(context.Background())
+	select {
+	case <-runner.Stopped():
+	case <-time.After(10 * time.Second):
+		t.Fatal("run over the error budget not stopped")
+	}
+	wait(t, doneCh)
+	if runner.Aborted() == "" {
+		t.Fatal("no abort reason")
+	}
+}
@@ -0,0 +1,294 @@
+package bench
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ontio/ontology/account"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/types"
+	vmtypes "github.com/ontio/ontology/vm/types"
+)
+
+// operation kinds of the workload mix
+const (
+	OP_ONT_TRANSFER = "ont"
+	OP_ONG_TRANSFER = "ong"
+	OP_ONG_WITHDRAW = "withdrawong"
+	OP_INVOKE       = "invoke"
+	OP_DEPLOY       = "deploy"
+	OP_REPLAY       = "replay"
+)
+
+// gas limit of an operation which does not set one, the gas price and limit
+// of a run are fixed and the nonce make every transaction unique
+const DEFAULT_GAS_LIMIT = 30000
+
+// OpClient submit the signed transactions of the operations
+type OpClient interface {
+	SendRawTransaction(tx *types.Transaction) (common.Uint256, error)
+}
+
+// TxBuilder build and sign the transactions of the operations locally
+type TxBuilder interface {
+	NewTransferTransaction(gasPrice, gasLimit uint64, asset string, from, to common.Address, amount uint64) (*types.Transaction, error)
+	NewWithdrawONGTransaction(gasPrice, gasLimit uint64, address common.Address, amount uint64) (*types.Transaction, error)
+	NewNeoVMSInvokeTransaction(gasPrice, gasLimit uint64, smartcodeAddress common.Address, params []interface{}) (*types.Transaction, error)
+	NewDeployCodeTransaction(gasPrice, gasLimit uint64, vmType vmtypes.VmType, code []byte, needStorage bool, cname, cversion, cauthor, cemail, cdesc string) *types.Transaction
+	SignToTransaction(tx *types.Transaction, signer *account.Account) error
+}
+
+// Operation is one kind of request of the workload mix
+type Operation struct {
+	Kind     string
+	weight   int
+	amount   uint64
+	to       common.Address
+	gasLimit uint64
+	contract common.Address
+	params   []interface{}
+	code     []byte
+	replay   *TxFile
+}
+
+func newOperation(cfg *OpConfig, to common.Address) (*Operation, error) {
+	op := &Operation{
+		Kind:     cfg.Kind,
+		weight:   cfg.Weight,
+		amount:   cfg.Amount,
+		to:       to,
+		gasLimit: cfg.GasLimit,
+		params:   convertParams(cfg.Params),
+	}
+	if op.weight <= 0 {
+		return nil, fmt.Errorf("invalid weight %d of %s", cfg.Weight, cfg.Kind)
+	}
+	if op.gasLimit == 0 {
+		op.gasLimit = DEFAULT_GAS_LIMIT
+	}
+	var err error
+	switch cfg.Kind {
+	case OP_ONT_TRANSFER, OP_ONG_TRANSFER, OP_ONG_WITHDRAW:
+		if op.amount == 0 {
+			op.amount = 1
+		}
+		if cfg.To != "" {
+			op.to, err = common.AddressFromBase58(cfg.To)
+			if err != nil {
+				return nil, fmt.Errorf("invalid to address %s:%s", cfg.To, err)
+			}
+		}
+	case OP_INVOKE:
+		op.contract, err = common.AddressFromHexString(cfg.Contract)
+		if err != nil {
+			return nil, fmt.Errorf("invalid contract address %s:%s", cfg.Contract, err)
+		}
+	case OP_DEPLOY:
+		op.code, err = hex.DecodeString(cfg.Code)
+		if err != nil || len(op.code) == 0 {
+			return nil, fmt.Errorf("invalid contract code:%v", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown operation kind %s", cfg.Kind)
+	}
+	return op, nil
+}
+
+// convertParams turn the json numbers of the params into integers
+func convertParams(params []interface{}) []interface{} {
+	ret := make([]interface{}, 0, len(params))
+	for _, param := range params {
+		switch v := param.(type) {
+		case float64:
+			ret = append(ret, int64(v))
+		case []interface{}:
+			ret = append(ret, convertParams(v))
+		default:
+			ret = append(ret, v)
+		}
+	}
+	return ret
+}
+
+// Execute sign the operation sent from from with nonce and submit it, the
+// hash of the transaction is returned even if the submission failed
+func (self *Operation) Execute(client OpClient, builder TxBuilder, from *account.Account, nonce uint32) (common.Uint256, error) {
+	tx, err := self.Sign(builder, from, nonce)
+	if err != nil {
+		return common.Uint256{}, err
+	}
+	return Submit(client, tx)
+}
+
+// Submit send tx through client, the hash of tx is returned even if the
+// submission failed since a timed out transaction may still be packed
+func Submit(client OpClient, tx *types.Transaction) (common.Uint256, error) {
+	hash, err := client.SendRawTransaction(tx)
+	if err != nil {
+		return tx.Hash(), err
+	}
+	return hash, nil
+}
+
+// Sign build the operation sent from from with nonce and sign it, replayed
+// transactions are signed already
+func (self *Operation) Sign(builder TxBuilder, from *account.Account, nonce uint32) (*types.Transaction, error) {
+	var tx *types.Transaction
+	var err error
+	switch self.Kind {
+	case OP_ONT_TRANSFER, OP_ONG_TRANSFER:
+		tx, err = builder.NewTransferTransaction(0, self.gasLimit, self.Kind, from.Address, self.to, self.amount)
+	case OP_ONG_WITHDRAW:
+		tx, err = builder.NewWithdrawONGTransaction(0, self.gasLimit, from.Address, self.amount)
+	case OP_INVOKE:
+		tx, err = builder.NewNeoVMSInvokeTransaction(0, self.gasLimit, self.contract, self.params)
+	case OP_DEPLOY:
+		//the same code can be deployed only once, append the unreachable
+		//sender and nonce after the code to get a new contract address
+		code := make([]byte, len(self.code), len(self.code)+len(from.Address)+4)
+		copy(code, self.code)
+		code = append(code, from.Address[:]...)
+		code = append(code, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(code[len(code)-4:], nonce)
+		tx = builder.NewDeployCodeTransaction(0, self.gasLimit, vmtypes.NEOVM, code, true,
+			"bench", "1.0", "ont-bench", "", "ont-bench contract")
+	case OP_REPLAY:
+		return self.replay.Next()
+	default:
+		return nil, fmt.Errorf("unknown operation kind %s", self.Kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("build %s transaction error:%s", self.Kind, err)
+	}
+	tx.Nonce = nonce
+	err = builder.SignToTransaction(tx, from)
+	if err != nil {
+		return nil, fmt.Errorf("SignToTransaction error:%s", err)
+	}
+	return tx, nil
+}
+
+// Effect is the balance change of an address made by an operation
+type Effect struct {
+	Address common.Address
+	Asset   string
+	Delta   int64
+}
+
+// Effects return the balance changes of the operation sent from from, gas
+// is free in the bench so only transfer and withdraw change balances
+func (self *Operation) Effects(from common.Address) []*Effect {
+	switch self.Kind {
+	case OP_ONT_TRANSFER, OP_ONG_TRANSFER:
+		return transferEffects(self.Kind, from, self.to, self.amount)
+	case OP_ONG_WITHDRAW:
+		return []*Effect{{Address: from, Asset: OP_ONG_TRANSFER, Delta: int64(self.amount)}}
+	}
+	return nil
+}
+
+func transferEffects(asset string, from, to common.Address, amount uint64) []*Effect {
+	return []*Effect{
+		{Address: from, Asset: asset, Delta: -int64(amount)},
+		{Address: to, Asset: asset, Delta: int64(amount)},
+	}
+}
+
+// Workload pick the operation of every task by the weight of the operations
+type Workload struct {
+	lock   sync.Mutex
+	rand   *rand.Rand
+	ops    []*Operation
+	weight int
+}
+
+// NewWorkload return the Workload of the operations in cfgs, to is the dest
+// address of the transfers which do not set one
+func NewWorkload(cfgs []*OpConfig, to common.Address) (*Workload, error) {
+	if len(cfgs) == 0 {
+		return nil, fmt.Errorf("empty workload")
+	}
+	self := &Workload{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	for _, cfg := range cfgs {
+		op, err := newOperation(cfg, to)
+		if err != nil {
+			return nil, err
+		}
+		self.ops = append(self.ops, op)
+		self.weight += op.weight
+	}
+	return self, nil
+}
+
+// DefaultWorkload return the Workload which only transfer 1 ont to to
+func DefaultWorkload(to common.Address) *Workload {
+	self, _ := NewWorkload([]*OpConfig{{Kind: OP_ONT_TRANSFER, Weight: 1, Amount: 1}}, to)
+	return self
+}
+
+// ReplayWorkload return the Workload which send the transactions of file in
+// order
+func ReplayWorkload(file *TxFile) *Workload {
+	op := &Operation{Kind: OP_REPLAY, weight: 1, replay: file}
+	return &Workload{
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
+		ops:    []*Operation{op},
+		weight: op.weight,
+	}
+}
+
+func (self *Workload) Next() *Operation {
+	self.lock.Lock()
+	n := self.rand.Intn(self.weight)
+	self.lock.Unlock()
+	for _, op := range self.ops {
+		if n < op.weight {
+			return op
+		}
+		n -= op.weight
+	}
+	return self.ops[len(self.ops)-1]
+}
+
+// Kinds return the kinds of the operations in the order of configuration
+func (self *Workload) Kinds() []string {
+	kinds := make([]string, 0, len(self.ops))
+	for _, op := range self.ops {
+		kinds = append(kinds, op.Kind)
+	}
+	return kinds
+}
+
+// Funds return the ont and ong a sender spend in count tasks, the amounts of
+// the transfers are taken by the weight of the operations
+func (self *Workload) Funds(count uint64) (ont, ong uint64) {
+	for _, op := range self.ops {
+		if op.Kind != OP_ONT_TRANSFER && op.Kind != OP_ONG_TRANSFER {
+			continue
+		}
+		total := count * op.amount * uint64(op.weight)
+		//round up so the share of every operation is covered
+		amount := (total + uint64(self.weight) - 1) / uint64(self.weight)
+		if op.Kind == OP_ONT_TRANSFER {
+			ont += amount
+		} else {
+			ong += amount
+		}
+	}
+	return ont, ong
+}
+
+// Destinations return the dest addresses of the transfers
+func (self *Workload) Destinations() []common.Address {
+	addrs := make([]common.Address, 0)
+	for _, op := range self.ops {
+		if op.Kind == OP_ONT_TRANSFER || op.Kind == OP_ONG_TRANSFER {
+			addrs = append(addrs, op.to)
+		}
+	}
+	return addrs
+}
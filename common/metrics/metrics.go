@@ -0,0 +1,257 @@
+// Package metrics expose counters, gauges and histograms in the prometheus
+// text format so stress runs can be watched from grafana
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DEFAULT_BUCKETS is the latency buckets in seconds of a histogram
+var DEFAULT_BUCKETS = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+type collector interface {
+	write(w io.Writer)
+}
+
+// Registry hold the metrics published by the handler
+type Registry struct {
+	lock       sync.Mutex
+	collectors []collector
+}
+
+var DefaultRegistry = &Registry{}
+
+func (self *Registry) register(c collector) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	self.collectors = append(self.collectors, c)
+}
+
+func (self *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	self.lock.Lock()
+	collectors := make([]collector, len(self.collectors))
+	copy(collectors, self.collectors)
+	self.lock.Unlock()
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+// Serve start serving the metrics of the DefaultRegistry at address/metrics
+func Serve(address string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", DefaultRegistry)
+	go func() {
+		err := http.ListenAndServe(address, mux)
+		if err != nil {
+			fmt.Printf("metrics server error:%s\n", err)
+		}
+	}()
+}
+
+// escaping of the text format, the help only escape the backslash and the
+// line feed while the label values escape the double quote as well
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func writeHeader(w io.Writer, name, help, typ string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, helpEscaper.Replace(help), name, typ)
+}
+
+func formatLabels(names, values []string, extra ...string) string {
+	pairs := make([]string, 0, len(names)+1)
+	for i, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(values[i])))
+	}
+	if len(extra) == 2 {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, extra[0], labelEscaper.Replace(extra[1])))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// vec keep the children of a metric by label values
+type vec struct {
+	lock     sync.Mutex
+	name     string
+	help     string
+	labels   []string
+	children map[string]interface{}
+	values   map[string][]string
+}
+
+func newVec(name, help string, labels []string) vec {
+	return vec{
+		name:     name,
+		help:     help,
+		labels:   labels,
+		children: make(map[string]interface{}),
+		values:   make(map[string][]string),
+	}
+}
+
+func (self *vec) child(values []string, create func() interface{}) interface{} {
+	if len(values) != len(self.labels) {
+		panic(fmt.Errorf("metric %s need %d label values, got %d", self.name, len(self.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	c, ok := self.children[key]
+	if !ok {
+		c = create()
+		self.children[key] = c
+		self.values[key] = append([]string{}, values...)
+	}
+	return c
+}
+
+// each call f on the children sorted by label values
+func (self *vec) each(f func(values []string, c interface{})) {
+	self.lock.Lock()
+	keys := make([]string, 0, len(self.children))
+	for key := range self.children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	children := make([]interface{}, len(keys))
+	values := make([][]string, len(keys))
+	for i, key := range keys {
+		children[i], values[i] = self.children[key], self.values[key]
+	}
+	self.lock.Unlock()
+	for i := range keys {
+		f(values[i], children[i])
+	}
+}
+
+type Counter struct {
+	lock  sync.Mutex
+	value float64
+}
+
+func (self *Counter) Add(v float64) {
+	self.lock.Lock()
+	self.value += v
+	self.lock.Unlock()
+}
+
+func (self *Counter) Inc() {
+	self.Add(1)
+}
+
+func (self *Counter) get() float64 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.value
+}
+
+type CounterVec struct {
+	vec
+}
+
+// NewCounterVec register a counter partitioned by labels
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	self := &CounterVec{newVec(name, help, labels)}
+	DefaultRegistry.register(self)
+	return self
+}
+
+func (self *CounterVec) WithLabelValues(values ...string) *Counter {
+	return self.child(values, func() interface{} { return &Counter{} }).(*Counter)
+}
+
+func (self *CounterVec) write(w io.Writer) {
+	writeHeader(w, self.name, self.help, "counter")
+	self.each(func(values []string, c interface{}) {
+		fmt.Fprintf(w, "%s%s %v\n", self.name, formatLabels(self.labels, values), c.(*Counter).get())
+	})
+}
+
+type Histogram struct {
+	lock    sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
+
+// Observe add a value, latencies are observed in seconds
+func (self *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(self.buckets, v)
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if i < len(self.counts) {
+		self.counts[i]++
+	}
+	self.count++
+	self.sum += v
+}
+
+type HistogramVec struct {
+	vec
+	buckets []float64
+}
+
+// NewHistogramVec register a histogram of buckets partitioned by labels
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	self := &HistogramVec{newVec(name, help, labels), buckets}
+	DefaultRegistry.register(self)
+	return self
+}
+
+func (self *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	return self.child(values, func() interface{} {
+		return &Histogram{buckets: self.buckets, counts: make([]uint64, len(self.buckets))}
+	}).(*Histogram)
+}
+
+func (self *HistogramVec) write(w io.Writer) {
+	writeHeader(w, self.name, self.help, "histogram")
+	self.each(func(values []string, c interface{}) {
+		h := c.(*Histogram)
+		h.lock.Lock()
+		defer h.lock.Unlock()
+		cumulative := uint64(0)
+		for i, le := range h.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", self.name, formatLabels(self.labels, values, "le", fmt.Sprint(le)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", self.name, formatLabels(self.labels, values, "le", "+Inf"), h.count)
+		fmt.Fprintf(w, "%s_sum%s %v\n", self.name, formatLabels(self.labels, values), h.sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", self.name, formatLabels(self.labels, values), h.count)
+	})
+}
+
+// funcMetric is a counter or gauge whose value is read on every scrape
+type funcMetric struct {
+	name string
+	help string
+	typ  string
+	f    func() float64
+}
+
+func (self *funcMetric) write(w io.Writer) {
+	writeHeader(w, self.name, self.help, self.typ)
+	fmt.Fprintf(w, "%s %v\n", self.name, self.f())
+}
+
+// NewCounterFunc register a counter whose value is read from f
+func NewCounterFunc(name, help string, f func() float64) {
+	DefaultRegistry.register(&funcMetric{name, help, "counter", f})
+}
+
+// NewGaugeFunc register a gauge whose value is read from f
+func NewGaugeFunc(name, help string, f func() float64) {
+	DefaultRegistry.register(&funcMetric{name, help, "gauge", f})
+}
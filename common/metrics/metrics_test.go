@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+const golden = `# HELP test_requests_total Requests by kind, "quoted" \\ and\nnewline
+# TYPE test_requests_total counter
+test_requests_total{kind="a\"b\\c\nd"} 1
+test_requests_total{kind="ont"} 2.5
+# HELP test_latency_seconds Latency in seconds
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{op="send",code="ok",le="0.125"} 2
+test_latency_seconds_bucket{op="send",code="ok",le="1"} 3
+test_latency_seconds_bucket{op="send",code="ok",le="+Inf"} 4
+test_latency_seconds_sum{op="send",code="ok"} 4.6875
+test_latency_seconds_count{op="send",code="ok"} 4
+# HELP test_pending Pending transactions
+# TYPE test_pending gauge
+test_pending 7
+# HELP test_received_total Received transactions
+# TYPE test_received_total counter
+test_received_total 1e+06
+`
+
+func TestExposition(t *testing.T) {
+	defer func(registry *Registry) { DefaultRegistry = registry }(DefaultRegistry)
+	DefaultRegistry = &Registry{}
+	requests := NewCounterVec("test_requests_total", "Requests by kind, \"quoted\" \\ and\nnewline", "kind")
+	requests.WithLabelValues("ont").Add(2.5)
+	requests.WithLabelValues("a\"b\\c\nd").Inc()
+	latency := NewHistogramVec("test_latency_seconds", "Latency in seconds", []float64{0.125, 1}, "op", "code")
+	h := latency.WithLabelValues("send", "ok")
+	//a value on a bucket bound fall into that bucket
+	for _, v := range []float64{0.0625, 0.125, 0.5, 4} {
+		h.Observe(v)
+	}
+	NewGaugeFunc("test_pending", "Pending transactions", func() float64 { return 7 })
+	NewCounterFunc("test_received_total", "Received transactions", func() float64 { return 1e6 })
+
+	w := httptest.NewRecorder()
+	DefaultRegistry.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if typ := w.Header().Get("Content-Type"); typ != "text/plain; version=0.0.4" {
+		t.Fatalf("content type %s", typ)
+	}
+	if got := w.Body.String(); got != golden {
+		t.Fatalf("exposition is\n%s\nwant\n%s", got, golden)
+	}
+}
@@ -0,0 +1,75 @@
+// Package report is the json report schema shared by ont-bench and
+// net-stress-test, so ont-bench compare read the reports of both
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"time"
+)
+
+// Header is the part of the report every tool write
+type Header struct {
+	Tool        string            `json:"Tool"`
+	Version     string            `json:"Version"`
+	Start       time.Time         `json:"Start"` //start of the measurement window
+	End         time.Time         `json:"End"`
+	Config      map[string]string `json:"Config"`
+	Environment *Environment      `json:"Environment"`
+}
+
+type Environment struct {
+	Hostname  string `json:"Hostname"`
+	GoVersion string `json:"GoVersion"`
+	OS        string `json:"OS"`
+	Arch      string `json:"Arch"`
+	NumCPU    int    `json:"NumCPU"`
+}
+
+type Summary struct {
+	Requests uint64  `json:"Requests"`
+	Success  uint64  `json:"Success"`
+	Errors   uint64  `json:"Errors"`
+	Seconds  float64 `json:"Seconds"`
+	TPS      float64 `json:"TPS"` //achieved successful requests per second
+}
+
+// SecondSummary is the requests of one second, the percentiles are left out
+// by the tools which do not measure latency
+type SecondSummary struct {
+	Second int     `json:"Second"`
+	Count  uint64  `json:"Count"`
+	P50    float64 `json:"P50,omitempty"`
+	P90    float64 `json:"P90,omitempty"`
+	P99    float64 `json:"P99,omitempty"`
+	Max    float64 `json:"Max,omitempty"`
+}
+
+// NewHeader return the Header of tool with the current environment
+func NewHeader(tool, version string) Header {
+	hostname, _ := os.Hostname()
+	return Header{
+		Tool:    tool,
+		Version: version,
+		Config:  make(map[string]string),
+		Environment: &Environment{
+			Hostname:  hostname,
+			GoVersion: runtime.Version(),
+			OS:        runtime.GOOS,
+			Arch:      runtime.GOARCH,
+			NumCPU:    runtime.NumCPU(),
+		},
+	}
+}
+
+// WriteJSON write the report v indented into file
+func WriteJSON(file string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("json.Marshal error:%s", err)
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/ontio/ontology-crypto/keypair"
 	"github.com/ontio/ontology-eventbus/actor"
 	tactor "github.com/ontio/ontology-stress-test/actor"
+	"github.com/ontio/ontology-stress-test/common/metrics"
 	"github.com/ontio/ontology/account"
 	//"github.com/ontio/ontology/cmd"
 	cmdcom "github.com/ontio/ontology/cmd/common"
@@ -37,6 +38,21 @@ import (
 	"github.com/urfave/cli"
 )
 
+var (
+	ReportFlag = cli.StringFlag{
+		Name:  "report",
+		Usage: "Write the json report of received transactions to the file on exit",
+	}
+	CsvFlag = cli.StringFlag{
+		Name:  "csv",
+		Usage: "Write the per second received transactions to the csv file on exit",
+	}
+	MetricsFlag = cli.StringFlag{
+		Name:  "metrics",
+		Usage: "Serve prometheus metrics at the address, e.g. :9101",
+	}
+)
+
 func setupAPP() *cli.App {
 	app := cli.NewApp()
 	app.Usage = "Ontology CLI"
@@ -74,6 +90,10 @@ func setupAPP() *cli.App {
 		//ws setting
 		utils.WsEnabledFlag,
 		utils.WsPortFlag,
+		//report setting
+		ReportFlag,
+		CsvFlag,
+		MetricsFlag,
 	}
 	app.Before = func(context *cli.Context) error {
 		runtime.GOMAXPROCS(runtime.NumCPU())
@@ -112,18 +132,23 @@ func startOntology(ctx *cli.Context) {
 		log.Errorf("initTxPool error:%s", err)
 		return
 	}
-	_, _, err = initP2PNode(ctx, wallet, txpool)
+	p2p, _, err := initP2PNode(ctx, wallet, txpool)
 	if err != nil {
 		log.Errorf("initP2PNode error:%s", err)
 		return
 	}
 	initRestful(ctx)
 	initWs(ctx)
+	initMetrics(ctx, p2p)
 
 	log.Info("wait for test data...")
 	go tactor.LoopPrintActorInfo()
 	//等待退出信号
 	waitToExit()
+	err = tactor.WriteTxnReport(ctx.GlobalString(ReportFlag.Name), ctx.GlobalString(CsvFlag.Name), ctx.App.Version)
+	if err != nil {
+		log.Errorf("WriteTxnReport error:%s", err)
+	}
 }
 
 func waitToExit() {
@@ -298,6 +323,23 @@ func initRestful(ctx *cli.Context) {
 	log.Infof("Restful init success")
 }
 
+func initMetrics(ctx *cli.Context, p2p *p2pserver.P2PServer) {
+	address := ctx.GlobalString(MetricsFlag.Name)
+	if address == "" {
+		return
+	}
+	tactor.EnableMetrics()
+	metrics.NewGaugeFunc("netstress_connected_peers", "Peers connected to the node", func() float64 {
+		if p2p == nil {
+			return 0
+		}
+		return float64(p2p.GetConnectionCnt())
+	})
+	metrics.Serve(address)
+
+	log.Infof("Metrics init success")
+}
+
 func initWs(ctx *cli.Context) {
 	if !ctx.GlobalBool(utils.WsEnabledFlag.Name) {
 		return
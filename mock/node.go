@@ -0,0 +1,383 @@
+// Package mock is a fake ontology node speaking the subset of the json rpc,
+// restful and websocket interfaces used by ont-bench, so the bench can be run
+// and tested without a live network
+package mock
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/types"
+)
+
+// error codes of the node's interfaces
+const (
+	ERR_SUCCESS             = 0
+	ERR_INVALID_PARAMS      = 42002
+	ERR_INVALID_METHOD      = 42003
+	ERR_INVALID_TRANSACTION = 43001
+	ERR_UNKNOWN_TRANSACTION = 44001
+	ERR_UNKNOWN_BLOCK       = 44003
+)
+
+// Config of the mock node
+type Config struct {
+	RpcAddress  string        //json rpc listen address, empty to disable, port 0 for any free one
+	RestAddress string        //restful listen address, empty to disable, port 0 for any free one
+	WsAddress   string        //websocket listen address, empty to disable, port 0 for any free one
+	Latency     time.Duration //delay of every request
+	Jitter      time.Duration //max random delay added to Latency
+	ErrorRate   float64       //percent of transactions rejected with ErrorDesc
+	ErrorDesc   string
+	DropRate    float64 //percent of requests whose connection is closed without response
+	BlockTime   time.Duration
+	MaxBlockTxs int    //max transactions of a block, 0 means unlimited
+	Ont         uint64 //ont balance of every address
+	Ong         uint64 //ong balance of every address
+}
+
+func DefaultConfig() *Config {
+	return &Config{
+		RpcAddress:  "127.0.0.1:20336",
+		RestAddress: "127.0.0.1:20334",
+		WsAddress:   "127.0.0.1:20335",
+		ErrorDesc:   "txpool is full",
+		BlockTime:   time.Second,
+		Ont:         1000000000,
+		Ong:         1000000000,
+	}
+}
+
+// Event is the smart contract event of an included transaction
+type Event struct {
+	TxHash      string
+	State       byte
+	GasConsumed uint64
+	Notify      []interface{}
+}
+
+type block struct {
+	raw    []byte
+	hash   common.Uint256
+	height uint32
+	txs    []common.Uint256
+}
+
+// Node keep the fake chain, transactions are accepted into the pool and
+// packed into a block every BlockTime
+type Node struct {
+	cfg      *Config
+	lock     sync.Mutex
+	rand     *rand.Rand
+	pool     []*types.Transaction
+	seen     map[common.Uint256]bool
+	events   map[common.Uint256]uint32 //tx hash to block height
+	blocks   []*block
+	subs     map[*wsSession]bool
+	sessions map[*wsSession]bool //open websocket connections, closed on Stop
+	servers  []*http.Server
+	addrs    map[string]string //bound address of every interface by name
+	stopCh   chan struct{}
+}
+
+func NewNode(cfg *Config) *Node {
+	self := &Node{
+		cfg:      cfg,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		seen:     make(map[common.Uint256]bool),
+		events:   make(map[common.Uint256]uint32),
+		subs:     make(map[*wsSession]bool),
+		sessions: make(map[*wsSession]bool),
+		addrs:    make(map[string]string),
+		stopCh:   make(chan struct{}),
+	}
+	self.pack(time.Now())
+	return self
+}
+
+// Start serve the configured interfaces and start producing blocks
+func (self *Node) Start() error {
+	handlers := []struct {
+		name    string
+		address string
+		handler http.Handler
+	}{
+		{"rpc", self.cfg.RpcAddress, http.HandlerFunc(self.serveRpc)},
+		{"rest", self.cfg.RestAddress, self.restHandler()},
+		{"ws", self.cfg.WsAddress, http.HandlerFunc(self.serveWs)},
+	}
+	for _, h := range handlers {
+		if h.address == "" {
+			continue
+		}
+		listener, err := net.Listen("tcp", h.address)
+		if err != nil {
+			self.Stop()
+			return fmt.Errorf("listen %s error:%s", h.address, err)
+		}
+		server := &http.Server{Handler: self.inject(h.handler)}
+		self.lock.Lock()
+		self.servers = append(self.servers, server)
+		self.addrs[h.name] = listener.Addr().String()
+		self.lock.Unlock()
+		go server.Serve(listener)
+	}
+	go self.produce()
+	return nil
+}
+
+// Stop close the servers and the websocket connections and stop producing
+// blocks
+func (self *Node) Stop() {
+	select {
+	case <-self.stopCh:
+		return
+	default:
+		close(self.stopCh)
+	}
+	self.lock.Lock()
+	servers := self.servers
+	sessions := make([]*wsSession, 0, len(self.sessions))
+	for s := range self.sessions {
+		sessions = append(sessions, s)
+	}
+	self.lock.Unlock()
+	//Close of a server does not close the hijacked websocket connections
+	for _, server := range servers {
+		server.Close()
+	}
+	for _, s := range sessions {
+		s.conn.Close()
+	}
+}
+
+// address return the bound address of the interface, empty if it is
+// disabled or not started
+func (self *Node) address(name string) string {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.addrs[name]
+}
+
+// RpcAddress return the address the json rpc interface listen on
+func (self *Node) RpcAddress() string {
+	return self.address("rpc")
+}
+
+// RestAddress return the address the restful interface listen on
+func (self *Node) RestAddress() string {
+	return self.address("rest")
+}
+
+// WsAddress return the address the websocket interface listen on
+func (self *Node) WsAddress() string {
+	return self.address("ws")
+}
+
+// inject delay every request and drop some of them as configured
+func (self *Node) inject(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		self.delay()
+		if self.hit(self.cfg.DropRate) {
+			if hj, ok := w.(http.Hijacker); ok {
+				conn, _, err := hj.Hijack()
+				if err == nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (self *Node) delay() {
+	d := self.cfg.Latency
+	if self.cfg.Jitter > 0 {
+		self.lock.Lock()
+		d += time.Duration(self.rand.Int63n(int64(self.cfg.Jitter)))
+		self.lock.Unlock()
+	}
+	if d > 0 {
+		time.Sleep(d)
+	}
+}
+
+// hit return true with the probability of percent
+func (self *Node) hit(percent float64) bool {
+	if percent <= 0 {
+		return false
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.rand.Float64()*100 < percent
+}
+
+func (self *Node) produce() {
+	ticker := time.NewTicker(self.cfg.BlockTime)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			self.pack(now)
+		case <-self.stopCh:
+			return
+		}
+	}
+}
+
+// pack the pooled transactions into a new block and push it to subscribers
+func (self *Node) pack(now time.Time) {
+	self.lock.Lock()
+	n := len(self.pool)
+	if self.cfg.MaxBlockTxs > 0 && n > self.cfg.MaxBlockTxs {
+		n = self.cfg.MaxBlockTxs
+	}
+	txs := self.pool[:n]
+	self.pool = self.pool[n:]
+	height := uint32(len(self.blocks))
+	blk := &types.Block{
+		Header: &types.Header{
+			Height:    height,
+			Timestamp: uint32(now.Unix()),
+		},
+		Transactions: txs,
+	}
+	if height > 0 {
+		blk.Header.PrevBlockHash = self.blocks[height-1].hash
+	}
+	buf := new(bytes.Buffer)
+	err := blk.Serialize(buf)
+	if err != nil {
+		self.lock.Unlock()
+		fmt.Printf("serialize block %d error:%s\n", height, err)
+		return
+	}
+	b := &block{raw: buf.Bytes(), hash: blk.Hash(), height: height}
+	for _, tx := range txs {
+		hash := tx.Hash()
+		b.txs = append(b.txs, hash)
+		self.events[hash] = height
+	}
+	self.blocks = append(self.blocks, b)
+	subs := make([]*wsSession, 0, len(self.subs))
+	for s := range self.subs {
+		subs = append(subs, s)
+	}
+	self.lock.Unlock()
+	for _, s := range subs {
+		s.pushBlock(b)
+	}
+}
+
+// SendRawTransaction accept the hex encoded transaction into the pool
+func (self *Node) SendRawTransaction(txHex string) (string, int64, string) {
+	raw, err := hex.DecodeString(txHex)
+	if err != nil {
+		return "", ERR_INVALID_PARAMS, "invalid tx hex"
+	}
+	tx := &types.Transaction{}
+	err = tx.Deserialize(bytes.NewReader(raw))
+	if err != nil {
+		return "", ERR_INVALID_TRANSACTION, fmt.Sprintf("invalid transaction:%s", err)
+	}
+	if self.hit(self.cfg.ErrorRate) {
+		return "", ERR_INVALID_TRANSACTION, self.cfg.ErrorDesc
+	}
+	hash := tx.Hash()
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if self.seen[hash] {
+		return "", ERR_INVALID_TRANSACTION, "duplicated transaction detected"
+	}
+	self.seen[hash] = true
+	self.pool = append(self.pool, tx)
+	return hash.ToHexString(), ERR_SUCCESS, ""
+}
+
+// PoolCount return the verified and verifying transactions in the pool, the
+// mock verify the transactions on submission
+func (self *Node) PoolCount() []uint32 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return []uint32{uint32(len(self.pool)), 0}
+}
+
+// InPool return whether the transaction is waiting in the pool
+func (self *Node) InPool(hashHex string) bool {
+	hash, err := common.Uint256FromHexString(hashHex)
+	if err != nil {
+		return false
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	_, packed := self.events[hash]
+	return self.seen[hash] && !packed
+}
+
+// BlockCount return the number of blocks including the genesis one
+func (self *Node) BlockCount() uint32 {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return uint32(len(self.blocks))
+}
+
+// BlockHex return the hex encoded block at height
+func (self *Node) BlockHex(height uint32) (string, bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if height >= uint32(len(self.blocks)) {
+		return "", false
+	}
+	return hex.EncodeToString(self.blocks[height].raw), true
+}
+
+// Balance return the balance of every address, the mock does not execute
+// the transactions so balances never change and a bench run with -audit
+// against it always report the transfers as lost
+func (self *Node) Balance() map[string]string {
+	return map[string]string{
+		"ont":        fmt.Sprint(self.cfg.Ont),
+		"ong":        fmt.Sprint(self.cfg.Ong),
+		"ong_appove": "0",
+	}
+}
+
+// TxEvent return the event of the transaction, nil if not included yet
+func (self *Node) TxEvent(hashHex string) *Event {
+	hash, err := common.Uint256FromHexString(hashHex)
+	if err != nil {
+		return nil
+	}
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if _, ok := self.events[hash]; !ok {
+		return nil
+	}
+	return newEvent(hash)
+}
+
+// BlockEvents return the events of the transactions in the block at height
+func (self *Node) BlockEvents(height uint32) ([]*Event, bool) {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	if height >= uint32(len(self.blocks)) {
+		return nil, false
+	}
+	events := make([]*Event, 0, len(self.blocks[height].txs))
+	for _, hash := range self.blocks[height].txs {
+		events = append(events, newEvent(hash))
+	}
+	return events, true
+}
+
+func newEvent(hash common.Uint256) *Event {
+	return &Event{TxHash: hash.ToHexString(), State: 1, Notify: []interface{}{}}
+}
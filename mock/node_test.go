@@ -0,0 +1,379 @@
+package mock
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	sdk "github.com/ontio/ontology-go-sdk"
+	"github.com/ontio/ontology/common"
+	"github.com/ontio/ontology/core/types"
+)
+
+// startNode start a mock node on any free loopback ports, blocks are only
+// packed by the tests unless cfg set a block time
+func startNode(t *testing.T, cfg *Config) *Node {
+	cfg.RpcAddress, cfg.RestAddress, cfg.WsAddress = "127.0.0.1:0", "127.0.0.1:0", "127.0.0.1:0"
+	node := NewNode(cfg)
+	if err := node.Start(); err != nil {
+		t.Fatalf("Start error:%s", err)
+	}
+	return node
+}
+
+func testConfig() *Config {
+	cfg := DefaultConfig()
+	cfg.BlockTime = time.Hour
+	return cfg
+}
+
+func newTx(t *testing.T, nonce uint32) *types.Transaction {
+	tx, err := sdk.NewOntologySdk().Rpc.NewTransferTransaction(0, 30000, "ont", common.Address{}, common.Address{}, 1)
+	if err != nil {
+		t.Fatalf("NewTransferTransaction error:%s", err)
+	}
+	tx.Nonce = nonce
+	return tx
+}
+
+func txHex(t *testing.T, tx *types.Transaction) string {
+	buf := new(bytes.Buffer)
+	if err := tx.Serialize(buf); err != nil {
+		t.Fatalf("Serialize error:%s", err)
+	}
+	return hex.EncodeToString(buf.Bytes())
+}
+
+// response is the common part of the rpc, restful and websocket responses
+type response struct {
+	Action string
+	Desc   string
+	Error  int64
+	Result json.RawMessage
+	Id     interface{}
+}
+
+func (self *response) result(t *testing.T, ret interface{}) {
+	if self.Error != ERR_SUCCESS {
+		t.Fatalf("%s error code:%d desc:%s", self.Action, self.Error, self.Desc)
+	}
+	if err := json.Unmarshal(self.Result, ret); err != nil {
+		t.Fatalf("invalid %s result %s:%s", self.Action, self.Result, err)
+	}
+}
+
+func post(t *testing.T, url string, body interface{}) (*response, error) {
+	data, _ := json.Marshal(body)
+	resp, err := http.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	ret := &response{}
+	if err := json.NewDecoder(resp.Body).Decode(ret); err != nil {
+		t.Fatalf("decode response error:%s", err)
+	}
+	return ret, nil
+}
+
+func get(t *testing.T, url string) *response {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("get %s error:%s", url, err)
+	}
+	defer resp.Body.Close()
+	ret := &response{}
+	if err := json.NewDecoder(resp.Body).Decode(ret); err != nil {
+		t.Fatalf("decode response error:%s", err)
+	}
+	return ret
+}
+
+func rpc(t *testing.T, node *Node, method string, params ...interface{}) (*response, error) {
+	return post(t, "http://"+node.RpcAddress(), map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+		"id":      1,
+	})
+}
+
+func mustRpc(t *testing.T, node *Node, method string, params ...interface{}) *response {
+	resp, err := rpc(t, node, method, params...)
+	if err != nil {
+		t.Fatalf("%s error:%s", method, err)
+	}
+	return resp
+}
+
+func TestRpc(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxBlockTxs = 2
+	node := startNode(t, cfg)
+	defer node.Stop()
+
+	hashes := make([]string, 0)
+	for i := uint32(1); i <= 3; i++ {
+		tx := newTx(t, i)
+		var hash string
+		mustRpc(t, node, "sendrawtransaction", txHex(t, tx)).result(t, &hash)
+		if hash != tx.Hash().ToHexString() {
+			t.Fatalf("hash of tx %d is %s, want %s", i, hash, tx.Hash().ToHexString())
+		}
+		hashes = append(hashes, hash)
+	}
+	if resp := mustRpc(t, node, "sendrawtransaction", txHex(t, newTx(t, 1))); resp.Error != ERR_INVALID_TRANSACTION {
+		t.Fatalf("duplicated transaction got error %d", resp.Error)
+	}
+	if resp := mustRpc(t, node, "sendrawtransaction", "zz"); resp.Error != ERR_INVALID_PARAMS {
+		t.Fatalf("invalid tx hex got error %d", resp.Error)
+	}
+	var event *Event
+	mustRpc(t, node, "getsmartcodeevent", hashes[0]).result(t, &event)
+	if event != nil {
+		t.Fatalf("event of a pooled transaction %+v", event)
+	}
+
+	//a block take at most MaxBlockTxs transactions
+	node.pack(time.Now())
+	node.pack(time.Now())
+	var count uint32
+	mustRpc(t, node, "getblockcount").result(t, &count)
+	if count != 3 {
+		t.Fatalf("block count %d, want 3", count)
+	}
+	for height, want := range []int{0, 2, 1} {
+		var raw string
+		mustRpc(t, node, "getblock", height).result(t, &raw)
+		data, err := hex.DecodeString(raw)
+		if err != nil {
+			t.Fatalf("invalid block hex:%s", err)
+		}
+		blk := &types.Block{}
+		if err := blk.Deserialize(bytes.NewReader(data)); err != nil {
+			t.Fatalf("Deserialize block %d error:%s", height, err)
+		}
+		if blk.Header.Height != uint32(height) || len(blk.Transactions) != want {
+			t.Fatalf("block %d has height %d and %d txs, want %d", height, blk.Header.Height, len(blk.Transactions), want)
+		}
+		var events []*Event
+		mustRpc(t, node, "getsmartcodeevent", height).result(t, &events)
+		if len(events) != want {
+			t.Fatalf("block %d has %d events, want %d", height, len(events), want)
+		}
+	}
+	if resp := mustRpc(t, node, "getblock", 3); resp.Error != ERR_UNKNOWN_BLOCK {
+		t.Fatalf("unknown block got error %d", resp.Error)
+	}
+	for _, hash := range hashes {
+		mustRpc(t, node, "getsmartcodeevent", hash).result(t, &event)
+		if event == nil || event.TxHash != hash || event.State != 1 {
+			t.Fatalf("event of %s is %+v", hash, event)
+		}
+	}
+
+	balance := make(map[string]string)
+	mustRpc(t, node, "getbalance", "AFmseVrdL9f9oyCzZefL9tG6UbvhUMqNMV").result(t, &balance)
+	if balance["ont"] != fmt.Sprint(cfg.Ont) || balance["ong"] != fmt.Sprint(cfg.Ong) {
+		t.Fatalf("balance %v, want ont %d ong %d", balance, cfg.Ont, cfg.Ong)
+	}
+	if resp := mustRpc(t, node, "getversion"); resp.Error != ERR_INVALID_METHOD {
+		t.Fatalf("unknown method got error %d", resp.Error)
+	}
+}
+
+func TestRest(t *testing.T) {
+	node := startNode(t, testConfig())
+	defer node.Stop()
+	url := "http://" + node.RestAddress() + "/api/v1"
+
+	tx := newTx(t, 1)
+	resp, err := post(t, url+"/transaction", map[string]string{
+		"Action":  "sendrawtransaction",
+		"Version": "1.0.0",
+		"Data":    txHex(t, tx),
+	})
+	if err != nil {
+		t.Fatalf("sendrawtransaction error:%s", err)
+	}
+	var hash string
+	resp.result(t, &hash)
+	if hash != tx.Hash().ToHexString() {
+		t.Fatalf("hash %s, want %s", hash, tx.Hash().ToHexString())
+	}
+	node.pack(time.Now())
+
+	var height uint32
+	get(t, url+"/block/height").result(t, &height)
+	if height != 1 {
+		t.Fatalf("block height %d, want 1", height)
+	}
+	var raw string
+	get(t, url+"/block/details/height/1?raw=1").result(t, &raw)
+	if raw == "" {
+		t.Fatal("empty raw block")
+	}
+	if resp := get(t, url+"/block/details/height/2?raw=1"); resp.Error != ERR_UNKNOWN_BLOCK {
+		t.Fatalf("unknown block got error %d", resp.Error)
+	}
+	var event *Event
+	get(t, url+"/smartcode/event/txhash/"+hash).result(t, &event)
+	if event == nil || event.TxHash != hash {
+		t.Fatalf("event of %s is %+v", hash, event)
+	}
+	var events []*Event
+	get(t, url+"/smartcode/event/transactions/1").result(t, &events)
+	if len(events) != 1 || events[0].TxHash != hash {
+		t.Fatalf("events of block 1 are %+v", events)
+	}
+	balance := make(map[string]string)
+	get(t, url+"/balance/AFmseVrdL9f9oyCzZefL9tG6UbvhUMqNMV").result(t, &balance)
+	if balance["ont"] != fmt.Sprint(node.cfg.Ont) {
+		t.Fatalf("balance %v, want ont %d", balance, node.cfg.Ont)
+	}
+}
+
+// readWs read the next message of conn which is not skipped
+func readWs(t *testing.T, conn *websocket.Conn, skip func(resp *response) bool) *response {
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		resp := &response{}
+		if err := conn.ReadJSON(resp); err != nil {
+			t.Fatalf("websocket read error:%s", err)
+		}
+		if !skip(resp) {
+			return resp
+		}
+	}
+}
+
+func TestWs(t *testing.T) {
+	node := startNode(t, testConfig())
+	defer node.Stop()
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+node.WsAddress(), nil)
+	if err != nil {
+		t.Fatalf("websocket dial error:%s", err)
+	}
+	defer conn.Close()
+	reply := func(id int) func(resp *response) bool {
+		return func(resp *response) bool {
+			n, ok := resp.Id.(float64)
+			return !ok || int(n) != id
+		}
+	}
+
+	conn.WriteJSON(map[string]interface{}{
+		"Action":                "subscribe",
+		"Version":               "1.0.0",
+		"Id":                    1,
+		"SubscribeBlockTxHashs": true,
+		"SubscribeEvent":        true,
+	})
+	readWs(t, conn, reply(1)).result(t, &map[string]bool{})
+	tx := newTx(t, 1)
+	conn.WriteJSON(map[string]interface{}{
+		"Action":  "sendrawtransaction",
+		"Version": "1.0.0",
+		"Id":      2,
+		"Data":    txHex(t, tx),
+	})
+	var hash string
+	readWs(t, conn, reply(2)).result(t, &hash)
+	if hash != tx.Hash().ToHexString() {
+		t.Fatalf("hash %s, want %s", hash, tx.Hash().ToHexString())
+	}
+
+	node.pack(time.Now())
+	push := func(action string) func(resp *response) bool {
+		return func(resp *response) bool {
+			return resp.Action != action
+		}
+	}
+	block := &wsBlockTxHashs{}
+	readWs(t, conn, push("sendblocktxhashs")).result(t, block)
+	if block.Height != 1 || len(block.Transactions) != 1 || block.Transactions[0] != hash {
+		t.Fatalf("pushed block %+v", block)
+	}
+	event := &Event{}
+	readWs(t, conn, push("Notify")).result(t, event)
+	if event.TxHash != hash {
+		t.Fatalf("pushed event %+v", event)
+	}
+
+	conn.WriteJSON(map[string]interface{}{"Action": "getblockheight", "Version": "1.0.0", "Id": 3})
+	var height uint32
+	readWs(t, conn, reply(3)).result(t, &height)
+	if height != 1 {
+		t.Fatalf("block height %d, want 1", height)
+	}
+	conn.WriteJSON(map[string]interface{}{"Action": "getversion", "Version": "1.0.0", "Id": 4})
+	if resp := readWs(t, conn, reply(4)); resp.Error != ERR_INVALID_METHOD {
+		t.Fatalf("unknown action got error %d", resp.Error)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	cfg := testConfig()
+	cfg.Latency = 50 * time.Millisecond
+	cfg.Jitter = 20 * time.Millisecond
+	node := startNode(t, cfg)
+	defer node.Stop()
+	for i := 0; i < 5; i++ {
+		start := time.Now()
+		mustRpc(t, node, "getblockcount")
+		d := time.Since(start)
+		if d < cfg.Latency {
+			t.Fatalf("request served in %v, want at least %v", d, cfg.Latency)
+		}
+	}
+}
+
+func TestErrorInjection(t *testing.T) {
+	cfg := testConfig()
+	cfg.ErrorRate = 100
+	cfg.ErrorDesc = "txpool is full"
+	node := startNode(t, cfg)
+	resp := mustRpc(t, node, "sendrawtransaction", txHex(t, newTx(t, 1)))
+	node.Stop()
+	if resp.Error != ERR_INVALID_TRANSACTION || resp.Desc != cfg.ErrorDesc {
+		t.Fatalf("rejected transaction got error %d desc %s", resp.Error, resp.Desc)
+	}
+
+	cfg = testConfig()
+	cfg.DropRate = 100
+	node = startNode(t, cfg)
+	_, err := rpc(t, node, "getblockcount")
+	node.Stop()
+	if err == nil {
+		t.Fatal("dropped request got a response")
+	}
+}
+
+func TestStopClosesWs(t *testing.T) {
+	node := startNode(t, testConfig())
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+node.WsAddress(), nil)
+	if err != nil {
+		t.Fatalf("websocket dial error:%s", err)
+	}
+	defer conn.Close()
+	conn.WriteJSON(map[string]interface{}{"Action": "getblockheight", "Version": "1.0.0", "Id": 1})
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&response{}); err != nil {
+		t.Fatalf("websocket read error:%s", err)
+	}
+	node.Stop()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if err == nil {
+		t.Fatal("websocket connection alive after Stop")
+	}
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		t.Fatal("websocket connection not closed by Stop")
+	}
+}
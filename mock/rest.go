@@ -0,0 +1,94 @@
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type restResponse struct {
+	Action  string
+	Desc    string
+	Error   int64
+	Result  interface{}
+	Version string
+}
+
+type restRequest struct {
+	Action  string
+	Version string
+	Data    string
+}
+
+func (self *Node) restHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/block/height", func(w http.ResponseWriter, r *http.Request) {
+		writeRest(w, "getblockheight", ERR_SUCCESS, "", self.BlockCount()-1)
+	})
+	mux.HandleFunc("/api/v1/block/details/height/", func(w http.ResponseWriter, r *http.Request) {
+		height, ok := heightPath(r.URL.Path)
+		if !ok || r.URL.Query().Get("raw") != "1" {
+			writeRest(w, "getblockbyheight", ERR_INVALID_PARAMS, "only raw block is supported", nil)
+			return
+		}
+		blk, ok := self.BlockHex(height)
+		if !ok {
+			writeRest(w, "getblockbyheight", ERR_UNKNOWN_BLOCK, "unknown block", nil)
+			return
+		}
+		writeRest(w, "getblockbyheight", ERR_SUCCESS, "", blk)
+	})
+	mux.HandleFunc("/api/v1/balance/", func(w http.ResponseWriter, r *http.Request) {
+		writeRest(w, "getbalance", ERR_SUCCESS, "", self.Balance())
+	})
+	mux.HandleFunc("/api/v1/smartcode/event/txhash/", func(w http.ResponseWriter, r *http.Request) {
+		hash := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		writeRest(w, "getsmartcodeeventbyhash", ERR_SUCCESS, "", self.TxEvent(hash))
+	})
+	mux.HandleFunc("/api/v1/smartcode/event/transactions/", func(w http.ResponseWriter, r *http.Request) {
+		height, ok := heightPath(r.URL.Path)
+		if !ok {
+			writeRest(w, "getsmartcodeeventbyheight", ERR_INVALID_PARAMS, "invalid height", nil)
+			return
+		}
+		events, ok := self.BlockEvents(height)
+		if !ok {
+			writeRest(w, "getsmartcodeeventbyheight", ERR_UNKNOWN_BLOCK, "unknown block", nil)
+			return
+		}
+		writeRest(w, "getsmartcodeeventbyheight", ERR_SUCCESS, "", events)
+	})
+	mux.HandleFunc("/api/v1/transaction", func(w http.ResponseWriter, r *http.Request) {
+		req := &restRequest{}
+		if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(req) != nil {
+			writeRest(w, "sendrawtransaction", ERR_INVALID_PARAMS, "invalid request", nil)
+			return
+		}
+		hash, code, desc := self.SendRawTransaction(req.Data)
+		writeRest(w, "sendrawtransaction", code, desc, hash)
+	})
+	return mux
+}
+
+func writeRest(w http.ResponseWriter, action string, code int64, desc string, result interface{}) {
+	if code == ERR_SUCCESS {
+		desc = "SUCCESS"
+	} else if result == nil || result == "" {
+		result = desc
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&restResponse{
+		Action:  action,
+		Desc:    desc,
+		Error:   code,
+		Result:  result,
+		Version: "1.0.0",
+	})
+}
+
+// heightPath return the block height at the end of the url path
+func heightPath(path string) (uint32, bool) {
+	height, err := strconv.ParseUint(path[strings.LastIndex(path, "/")+1:], 10, 32)
+	return uint32(height), err == nil
+}
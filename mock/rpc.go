@@ -0,0 +1,129 @@
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type rpcRequest struct {
+	Version string        `json:"jsonrpc"`
+	Method  string        `json:"method"`
+	Params  []interface{} `json:"params"`
+	Id      interface{}   `json:"id"`
+}
+
+type rpcResponse struct {
+	Version string      `json:"jsonrpc"`
+	Error   int64       `json:"error"`
+	Desc    string      `json:"desc"`
+	Result  interface{} `json:"result"`
+	Id      interface{} `json:"id"`
+}
+
+func (self *Node) serveRpc(w http.ResponseWriter, r *http.Request) {
+	req := &rpcRequest{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil {
+		writeRpc(w, req, ERR_INVALID_PARAMS, "invalid json request", nil)
+		return
+	}
+	switch req.Method {
+	case "getbalance":
+		writeRpc(w, req, ERR_SUCCESS, "", self.Balance())
+	case "sendrawtransaction":
+		txHex, ok := stringParam(req.Params, 0)
+		if !ok {
+			writeRpc(w, req, ERR_INVALID_PARAMS, "invalid params", nil)
+			return
+		}
+		hash, code, desc := self.SendRawTransaction(txHex)
+		writeRpc(w, req, code, desc, hash)
+	case "getblockcount":
+		writeRpc(w, req, ERR_SUCCESS, "", self.BlockCount())
+	case "getblock":
+		height, ok := heightParam(req.Params, 0)
+		if !ok {
+			writeRpc(w, req, ERR_INVALID_PARAMS, "only block height is supported", nil)
+			return
+		}
+		blk, ok := self.BlockHex(height)
+		if !ok {
+			writeRpc(w, req, ERR_UNKNOWN_BLOCK, "unknown block", nil)
+			return
+		}
+		writeRpc(w, req, ERR_SUCCESS, "", blk)
+	case "getsmartcodeevent":
+		if height, ok := heightParam(req.Params, 0); ok {
+			events, ok := self.BlockEvents(height)
+			if !ok {
+				writeRpc(w, req, ERR_UNKNOWN_BLOCK, "unknown block", nil)
+				return
+			}
+			writeRpc(w, req, ERR_SUCCESS, "", events)
+			return
+		}
+		hash, ok := stringParam(req.Params, 0)
+		if !ok {
+			writeRpc(w, req, ERR_INVALID_PARAMS, "invalid params", nil)
+			return
+		}
+		writeRpc(w, req, ERR_SUCCESS, "", self.TxEvent(hash))
+	case "getmempooltxcount":
+		writeRpc(w, req, ERR_SUCCESS, "", self.PoolCount())
+	case "getmempooltxstate":
+		hash, ok := stringParam(req.Params, 0)
+		if !ok {
+			writeRpc(w, req, ERR_INVALID_PARAMS, "invalid params", nil)
+			return
+		}
+		if !self.InPool(hash) {
+			writeRpc(w, req, ERR_UNKNOWN_TRANSACTION, "unknown transaction", nil)
+			return
+		}
+		//verified by both the stateless and the stateful validators
+		state := map[string]interface{}{"State": []map[string]interface{}{
+			{"Type": 0, "Height": 0, "ErrCode": 0},
+			{"Type": 1, "Height": self.BlockCount(), "ErrCode": 0},
+		}}
+		writeRpc(w, req, ERR_SUCCESS, "", state)
+	default:
+		writeRpc(w, req, ERR_INVALID_METHOD, "method "+req.Method+" not supported", nil)
+	}
+}
+
+func writeRpc(w http.ResponseWriter, req *rpcRequest, code int64, desc string, result interface{}) {
+	if code == ERR_SUCCESS {
+		desc = "SUCCESS"
+	} else if result == nil || result == "" {
+		//the sdk only print the result of a failed request
+		result = desc
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(&rpcResponse{
+		Version: "2.0",
+		Error:   code,
+		Desc:    desc,
+		Result:  result,
+		Id:      req.Id,
+	})
+}
+
+func stringParam(params []interface{}, i int) (string, bool) {
+	if i >= len(params) {
+		return "", false
+	}
+	s, ok := params[i].(string)
+	return s, ok
+}
+
+// heightParam return the param as block height, json numbers are float64
+func heightParam(params []interface{}, i int) (uint32, bool) {
+	if i >= len(params) {
+		return 0, false
+	}
+	f, ok := params[i].(float64)
+	if !ok || f < 0 {
+		return 0, false
+	}
+	return uint32(f), true
+}
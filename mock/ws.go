@@ -0,0 +1,165 @@
+package mock
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+
+type wsRequest struct {
+	Action                string
+	Version               string
+	Id                    interface{}
+	Data                  string
+	Hash                  string
+	Height                uint32
+	Raw                   string
+	SubscribeBlockTxHashs bool
+	SubscribeEvent        bool
+}
+
+type wsResponse struct {
+	Action  string
+	Desc    string
+	Error   int64
+	Result  interface{}
+	Version string
+	Id      interface{} `json:",omitempty"`
+}
+
+type wsBlockTxHashs struct {
+	Hash         string
+	Height       uint32
+	Transactions []string
+}
+
+// wsSession is a websocket connection and its subscriptions
+type wsSession struct {
+	lock        sync.Mutex
+	conn        *websocket.Conn
+	blockHashes bool
+	events      bool
+}
+
+func (self *wsSession) write(resp *wsResponse) error {
+	self.lock.Lock()
+	defer self.lock.Unlock()
+	return self.conn.WriteJSON(resp)
+}
+
+func (self *wsSession) reply(req *wsRequest, code int64, desc string, result interface{}) error {
+	if code == ERR_SUCCESS {
+		desc = "SUCCESS"
+	} else if result == nil || result == "" {
+		result = desc
+	}
+	return self.write(&wsResponse{
+		Action:  req.Action,
+		Desc:    desc,
+		Error:   code,
+		Result:  result,
+		Version: "1.0.0",
+		Id:      req.Id,
+	})
+}
+
+func (self *wsSession) pushBlock(b *block) {
+	self.lock.Lock()
+	blockHashes, events := self.blockHashes, self.events
+	self.lock.Unlock()
+	if blockHashes {
+		hashes := make([]string, 0, len(b.txs))
+		for _, hash := range b.txs {
+			hashes = append(hashes, hash.ToHexString())
+		}
+		self.write(&wsResponse{
+			Action:  "sendblocktxhashs",
+			Desc:    "SUCCESS",
+			Result:  &wsBlockTxHashs{Hash: b.hash.ToHexString(), Height: b.height, Transactions: hashes},
+			Version: "1.0.0",
+		})
+	}
+	if events {
+		for _, hash := range b.txs {
+			self.write(&wsResponse{
+				Action:  "Notify",
+				Desc:    "SUCCESS",
+				Result:  newEvent(hash),
+				Version: "1.0.0",
+			})
+		}
+	}
+}
+
+func (self *Node) serveWs(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	session := &wsSession{conn: conn}
+	self.lock.Lock()
+	self.sessions[session] = true
+	self.lock.Unlock()
+	defer func() {
+		self.lock.Lock()
+		delete(self.subs, session)
+		delete(self.sessions, session)
+		self.lock.Unlock()
+		conn.Close()
+	}()
+	for {
+		req := &wsRequest{}
+		if err := conn.ReadJSON(req); err != nil {
+			return
+		}
+		self.delay()
+		if err := self.serveWsRequest(session, req); err != nil {
+			return
+		}
+	}
+}
+
+func (self *Node) serveWsRequest(session *wsSession, req *wsRequest) error {
+	switch req.Action {
+	case "subscribe":
+		session.lock.Lock()
+		session.blockHashes = req.SubscribeBlockTxHashs
+		session.events = req.SubscribeEvent
+		session.lock.Unlock()
+		self.lock.Lock()
+		self.subs[session] = true
+		self.lock.Unlock()
+		return session.reply(req, ERR_SUCCESS, "", map[string]bool{
+			"SubscribeBlockTxHashs": req.SubscribeBlockTxHashs,
+			"SubscribeEvent":        req.SubscribeEvent,
+		})
+	case "sendrawtransaction":
+		hash, code, desc := self.SendRawTransaction(req.Data)
+		return session.reply(req, code, desc, hash)
+	case "getblockheight":
+		return session.reply(req, ERR_SUCCESS, "", self.BlockCount()-1)
+	case "getbalance":
+		return session.reply(req, ERR_SUCCESS, "", self.Balance())
+	case "getblockbyheight":
+		if req.Raw != "1" {
+			return session.reply(req, ERR_INVALID_PARAMS, "only raw block is supported", nil)
+		}
+		blk, ok := self.BlockHex(req.Height)
+		if !ok {
+			return session.reply(req, ERR_UNKNOWN_BLOCK, "unknown block", nil)
+		}
+		return session.reply(req, ERR_SUCCESS, "", blk)
+	case "getsmartcodeeventbyhash":
+		return session.reply(req, ERR_SUCCESS, "", self.TxEvent(req.Hash))
+	case "getsmartcodeeventbyheight":
+		events, ok := self.BlockEvents(req.Height)
+		if !ok {
+			return session.reply(req, ERR_UNKNOWN_BLOCK, "unknown block", nil)
+		}
+		return session.reply(req, ERR_SUCCESS, "", events)
+	}
+	return session.reply(req, ERR_INVALID_METHOD, "action "+req.Action+" not supported", nil)
+}
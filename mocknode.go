@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ontio/ontology-stress-test/mock"
+)
+
+var cfg = mock.DefaultConfig()
+
+func init() {
+	flag.StringVar(&cfg.RpcAddress, "rpc", cfg.RpcAddress, "Json rpc listen address, empty to disable")
+	flag.StringVar(&cfg.RestAddress, "rest", cfg.RestAddress, "Restful listen address, empty to disable")
+	flag.StringVar(&cfg.WsAddress, "ws", cfg.WsAddress, "Websocket listen address, empty to disable")
+	flag.DurationVar(&cfg.Latency, "latency", cfg.Latency, "Delay of every request")
+	flag.DurationVar(&cfg.Jitter, "jitter", cfg.Jitter, "Max random delay added to -latency")
+	flag.Float64Var(&cfg.ErrorRate, "errrate", cfg.ErrorRate, "Percent of transactions rejected with -errdesc")
+	flag.StringVar(&cfg.ErrorDesc, "errdesc", cfg.ErrorDesc, "Error message of the rejected transactions")
+	flag.Float64Var(&cfg.DropRate, "droprate", cfg.DropRate, "Percent of requests whose connection is closed without response")
+	flag.DurationVar(&cfg.BlockTime, "blocktime", cfg.BlockTime, "Block interval")
+	flag.IntVar(&cfg.MaxBlockTxs, "blocktxs", cfg.MaxBlockTxs, "Max transactions of a block, 0 means unlimited")
+	flag.Uint64Var(&cfg.Ont, "ont", cfg.Ont, "Ont balance of every address")
+	flag.Uint64Var(&cfg.Ong, "ong", cfg.Ong, "Ong balance of every address")
+	flag.Usage = func() {
+		fmt.Println("Usage: ont-mock [options]")
+		fmt.Println("The transactions are not executed, balances stay at -ont and -ong, so ont-bench -audit fails against the mock")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+}
+
+func main() {
+	node := mock.NewNode(cfg)
+	err := node.Start()
+	if err != nil {
+		fmt.Printf("Start mock node error:%s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Mock node started, rpc:%s rest:%s ws:%s:%v\n", node.RpcAddress(), node.RestAddress(), node.WsAddress(), time.Now())
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM)
+	<-sc
+	node.Stop()
+	fmt.Printf("Mock node stopped, %d blocks:%v\n", node.BlockCount(), time.Now())
+}
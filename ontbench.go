@@ -1,143 +1,1054 @@
 package main
 
 import (
+	"context"
+	"encoding/hex"
 	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/ontio/ontology-crypto/keypair"
 	sdk "github.com/ontio/ontology-go-sdk"
+	"github.com/ontio/ontology-stress-test/bench"
+	"github.com/ontio/ontology-stress-test/common/config"
+	"github.com/ontio/ontology-stress-test/common/metrics"
 	"github.com/ontio/ontology/account"
 	"github.com/ontio/ontology/common"
 	"github.com/ontio/ontology/common/log"
 )
 
 var (
-	COUNT       int
-	TPS         int
-	WORKER      int
-	RPC         string
-	TO          string
-	WALLET_FILE string
-	WALLET_PWD  string
+	COUNT          int
+	TPS            int
+	WORKER         int
+	QUEUE          int
+	RPC            string
+	TO             string
+	WALLET_FILE    string
+	WALLET_PWD     string
+	CONFIRM        string
+	WS             string
+	DEADLINE       time.Duration
+	POLL           time.Duration
+	SENDERS        int
+	SENDER_FILE    string
+	FUND           uint64
+	SCENARIO       string
+	PROFILE        string
+	LB             string
+	REPORT         string
+	CSV            string
+	METRICS        string
+	DURATION       time.Duration
+	GRACE          time.Duration
+	ERR_BUDGET     float64
+	AUDIT          bool
+	REPLAY         string
+	TRANSPORT      string
+	REST           string
+	SIGNERS        int
+	SIGN_BUF       int
+	KEY_TYPE       string
+	MODEL          string
+	THINK          time.Duration
+	SHARE          float64
+	WARMUP         time.Duration
+	WARMUP_CNT     int
+	BLOCKS         string
+	GEN_BLOCK_TIME time.Duration
+	MEMPOOL        time.Duration
+	MIN_TPS        float64
+	MAX_P99        time.Duration
+	MAX_ERRORS     float64
+	MIN_CONFIRM    float64
+	SENDER_OFF     int
 )
 
 var (
-	OntSdk *sdk.OntologySdk
-	Admin  *account.Account
+	OntSdk    *sdk.OntologySdk
+	Admin     *account.Account
+	Confirmer *bench.Confirmer
+	Analyzer  *bench.Analyzer
+	Mempool   *bench.Mempool
+	Senders   *bench.SenderPool
+	Workload  *bench.Workload
+	Profile   *bench.Profile
+	Endpoints *bench.EndpointPool
+	Rate      *bench.RateController
+	Stats     *bench.Stats
+	Auditor   *bench.Auditor
+	Signer    *bench.Signer
+	Loop      *bench.ClosedLoop
+	Nonces    *bench.Nonces
+	Runner    *bench.Runner
+	Agent     *bench.Agent
+	AgentPlan *bench.Plan
 )
 
 func init() {
 	flag.IntVar(&COUNT, "r", 100000, "Request count")
 	flag.IntVar(&TPS, "tps", 1000, "tx per second")
-	flag.IntVar(&WORKER, "w", 10, "Worker num")
-	flag.StringVar(&RPC, "rpc", "http://localhost:20336", "Default address of ontology rpc")
+	flag.IntVar(&WORKER, "w", 10, "Worker num, the virtual users of the closed model")
+	flag.IntVar(&QUEUE, "queue", 10000, "Max scheduled tasks waiting for a free worker")
+	flag.StringVar(&RPC, "rpc", "http://localhost:20336", "Comma separated addresses of ontology rpc")
 	flag.StringVar(&TO, "to", "", "Dest address")
 	flag.StringVar(&WALLET_FILE, "wallet", "./wallet.dat", "Wallet file path")
 	flag.StringVar(&WALLET_PWD, "pwd", "pwd", "Password of wallet")
+	flag.StringVar(&CONFIRM, "confirm", "", "Track block inclusion of transfers by polling rpc(poll) or subscribing websocket(ws)")
+	flag.StringVar(&WS, "ws", "ws://localhost:20335", "Comma separated addresses of ontology websocket, blocks are subscribed from the first one")
+	flag.StringVar(&REST, "rest", "http://localhost:20334", "Comma separated addresses of ontology restful")
+	flag.StringVar(&TRANSPORT, "transport", bench.TRANSPORT_RPC, "Submit transactions through rpc, rest or ws")
+	flag.DurationVar(&DEADLINE, "deadline", time.Minute, "Max wait for a transfer to be included in a block")
+	flag.DurationVar(&POLL, "poll", time.Second, "Block height polling interval")
+	flag.IntVar(&SENDERS, "senders", 0, "Sender account num, 0 means send from the default account")
+	flag.StringVar(&SENDER_FILE, "senderwallet", "", "Wallet file to load sender accounts from, generate new accounts if empty")
+	flag.Uint64Var(&FUND, "fund", 0, "Ont funded to each sender, 0 means enough for its share of requests, ong is funded by the workload")
+	flag.StringVar(&SCENARIO, "scenario", "", "Scenario file describing the workload mix")
+	flag.StringVar(&PROFILE, "profile", "", "Load profile overriding -tps, e.g. ramp:100:2000:60s,const:2000:5m")
+	flag.StringVar(&LB, "lb", bench.LB_ROUND_ROBIN, "Load balance strategy of rpc addresses: roundrobin, random, least or sticky")
+	flag.StringVar(&REPORT, "report", "", "Write the json report of the run to the file")
+	flag.StringVar(&CSV, "csv", "", "Write the per second series of the run to the csv file")
+	flag.StringVar(&METRICS, "metrics", "", "Serve prometheus metrics at the address, e.g. :9100")
+	flag.DurationVar(&DURATION, "duration", 0, "Run duration, -r is unlimited unless given explicitly")
+	flag.DurationVar(&GRACE, "grace", 10*time.Second, "Max wait for in-flight requests after the run is stopped")
+	flag.Float64Var(&ERR_BUDGET, "errbudget", 0, "Abort the run when more than the percent of requests failed, 0 means never")
+	flag.BoolVar(&AUDIT, "audit", false, "Check the balance changes of senders and recipients after the run")
+	flag.StringVar(&REPLAY, "replay", "", "Replay the pre-signed transactions of the file generated by testcli, -r default to all of them")
+	flag.IntVar(&SIGNERS, "signers", 0, "Signing worker num, sign transfers ahead of the submitting workers if not 0")
+	flag.IntVar(&SIGN_BUF, "signbuf", 1000, "Max signed transactions waiting for a submitting worker")
+	flag.StringVar(&KEY_TYPE, "keytype", bench.KEY_TYPE_ECDSA, "Key type of the senders generated by -senders without -senderwallet and of the bootstrap sender wallet: ecdsa, sm2 or ed25519")
+	flag.StringVar(&MODEL, "model", bench.MODEL_OPEN, "Load model: open sends at -tps or -profile, closed runs -w virtual users sending one request at a time")
+	flag.DurationVar(&THINK, "think", 0, "Think time of a virtual user between requests of the closed model")
+	flag.Float64Var(&SHARE, "share", 1, "Share of the target rate run by this process, set by the coordinator")
+	flag.IntVar(&SENDER_OFF, "senderoffset", 0, "Index of the first sender account in -senderwallet")
+	flag.DurationVar(&WARMUP, "warmup", 0, "Warmup duration before -duration, the load is applied but excluded from the statistics")
+	flag.IntVar(&WARMUP_CNT, "warmupcount", 0, "Warmup requests before -r, excluded from the statistics")
+	flag.StringVar(&BLOCKS, "blocks", "", "Analyze the committed throughput of the blocks followed by polling rpc(poll) or subscribing websocket(ws)")
+	genBlockTime := config.Parameters.GenBlockTime
+	if genBlockTime == 0 {
+		genBlockTime = config.DEFAULT_GEN_BLOCK_TIME
+	}
+	flag.Float64Var(&MIN_TPS, "mintps", 0, "SLO: min achieved tps, exit with 3 if violated")
+	flag.DurationVar(&MAX_P99, "maxp99", 0, "SLO: max p99 latency")
+	flag.Float64Var(&MAX_ERRORS, "maxerrors", 0, "SLO: max error rate in percent")
+	flag.Float64Var(&MIN_CONFIRM, "minconfirm", 0, "SLO: min confirmed of the tracked transactions in percent, needs -confirm")
+	flag.DurationVar(&MEMPOOL, "mempool", 0, "Interval of sampling the txpool depth through the first -rpc, 0 to disable")
+	flag.DurationVar(&GEN_BLOCK_TIME, "genblocktime", time.Duration(genBlockTime)*time.Second, "Block time of the node, GenBlockTime of config.json by default")
 	flag.Parse()
+	adjustFlags()
+}
+
+// adjustFlags apply the defaults depending on other flags
+func adjustFlags() {
+	if DURATION > 0 || REPLAY != "" {
+		rSet := false
+		flag.Visit(func(f *flag.Flag) {
+			rSet = rSet || f.Name == "r"
+		})
+		if !rSet {
+			COUNT = 0
+		}
+	}
 }
 
 func main() {
+	switch flag.Arg(0) {
+	case "compare":
+		os.Exit(compareReports(flag.Args()[1:]))
+	case "agent":
+		os.Exit(runAgent(flag.Args()[1:]))
+	case "coordinator":
+		os.Exit(coordinate(flag.Args()[1:]))
+	case "bootstrap":
+		os.Exit(bootstrap(flag.Args()[1:]))
+	}
+	os.Exit(runBench())
+}
+
+// totalCount return the requests of the run, 0 means unlimited. The warmup
+// requests come on top of the measured ones except for a replay, whose file
+// is the whole run
+func totalCount() int {
+	if COUNT <= 0 || REPLAY != "" {
+		return COUNT
+	}
+	return COUNT + WARMUP_CNT
+}
+
+// runBench run the bench of the flags, the share of a distributed run if the
+// process is an agent. It return the exit code of the process
+func runBench() int {
 	log.InitLog(log.InfoLog)
+	if METRICS != "" {
+		bench.EnableMetrics()
+		metrics.Serve(METRICS)
+	}
+	keyTypeSet := false
+	flag.Visit(func(f *flag.Flag) {
+		keyTypeSet = keyTypeSet || f.Name == "keytype"
+	})
+	if keyTypeSet && (SENDERS == 0 || SENDER_FILE != "") {
+		fmt.Println("-keytype only apply to the senders generated by -senders without -senderwallet")
+		return 1
+	}
 	OntSdk = sdk.NewOntologySdk()
-	OntSdk.Rpc.SetAddress(RPC)
+	OntSdk.Rpc.SetAddress(strings.Split(RPC, ",")[0])
+	var err error
+	Endpoints, err = newEndpoints()
+	if err != nil {
+		fmt.Printf("NewEndpointPool error:%s\n", err)
+		return 1
+	}
 	wallet, err := OntSdk.OpenWallet(WALLET_FILE)
 	if err != nil {
 		fmt.Printf("OpenWallet error:%s\n", err)
-		return
+		return 1
 	}
 	Admin, err = wallet.GetDefaultAccount([]byte(WALLET_PWD))
 	if err != nil {
 		fmt.Printf("CreateAccount error:%s", err)
-		return
+		return 1
 	}
 	fmt.Printf("Admin:%x\n", keypair.SerializePublicKey(Admin.PublicKey))
 
 	balance, err := OntSdk.Rpc.GetBalance(Admin.Address)
 	if err != nil {
 		fmt.Printf("GetBalance error:%s\n", err)
-		return
+		return 1
 	}
 
 	fmt.Printf("Admin ont balance:%d\n", balance.Ont)
-	if balance.Ont < 0 {
-		fmt.Printf("Admin balance not enought\n")
-		return
-	}
 
-	if TO == "" {
-		fmt.Println("Dest address should not be nil")
-		return
+	scenario := &bench.Scenario{}
+	if SCENARIO != "" {
+		scenario, err = bench.LoadScenario(SCENARIO)
+		if err != nil {
+			fmt.Printf("LoadScenario error:%s\n", err)
+			return 1
+		}
+	}
+	if REPLAY != "" {
+		if SENDERS > 0 || AUDIT || len(scenario.Workload) > 0 {
+			fmt.Println("-senders, -audit and scenario workload can not be used with -replay")
+			return 1
+		}
+		txFile, err := bench.OpenTxFile(REPLAY)
+		if err != nil {
+			fmt.Printf("OpenTxFile error:%s\n", err)
+			return 1
+		}
+		if COUNT <= 0 || COUNT > txFile.Count() {
+			COUNT = txFile.Count()
+		}
+		defer txFile.Close()
+		fmt.Printf("Replay %d of %d transactions in %s\n", COUNT, txFile.Count(), REPLAY)
+		Workload = bench.ReplayWorkload(txFile)
+		if CONFIRM == "" {
+			//the replayed hashes are always checked for inclusion
+			CONFIRM = "poll"
+		}
+	} else {
+		if TO == "" {
+			fmt.Println("Dest address should not be nil")
+			return 1
+		}
+		toAcc, err := common.AddressFromBase58(TO)
+		if err != nil {
+			fmt.Printf("Invalid dest address:%s\n", err)
+			return 1
+		}
+		Workload = bench.DefaultWorkload(toAcc)
+		if len(scenario.Workload) > 0 {
+			Workload, err = bench.NewWorkload(scenario.Workload, toAcc)
+			if err != nil {
+				fmt.Printf("NewWorkload error:%s\n", err)
+				return 1
+			}
+		}
+	}
+	switch MODEL {
+	case bench.MODEL_OPEN:
+		if TPS <= 0 && PROFILE == "" && len(scenario.Profile) == 0 {
+			fmt.Printf("invalid tps %d of the open model\n", TPS)
+			return 1
+		}
+	case bench.MODEL_CLOSED:
+		if SIGNERS > 0 || PROFILE != "" || len(scenario.Profile) > 0 {
+			fmt.Println("-signers and load profile can not be used with the closed model")
+			return 1
+		}
+	default:
+		fmt.Printf("unknown model %s\n", MODEL)
+		return 1
+	}
+	Profile = bench.ConstProfile(float64(TPS))
+	if PROFILE != "" {
+		scenario.Profile, err = bench.ParseProfile(PROFILE)
+		if err != nil {
+			fmt.Printf("ParseProfile error:%s\n", err)
+			return 1
+		}
+	}
+	if len(scenario.Profile) > 0 {
+		Profile, err = bench.NewProfile(scenario.Profile)
+		if err != nil {
+			fmt.Printf("NewProfile error:%s\n", err)
+			return 1
+		}
+	}
+	if SHARE != 1 {
+		Profile = Profile.Scale(SHARE)
+	}
+	if SENDERS > 0 {
+		if SENDER_FILE != "" {
+			Senders, err = loadSenders(SENDER_FILE, SENDERS)
+			if err != nil {
+				fmt.Printf("Load senders error:%s\n", err)
+				return 1
+			}
+		} else {
+			//keep the keys on disk so the funds are not lost with the process
+			file := fmt.Sprintf("./senders-%d.dat", time.Now().UnixNano())
+			accounts, err := createWallet(file, SENDERS, KEY_TYPE)
+			if err != nil {
+				fmt.Printf("Generate senders error:%s\n", err)
+				return 1
+			}
+			Senders = bench.NewSenderPool(accounts)
+			fmt.Printf("Generated %d senders into %s, reuse them with -senderwallet %s\n", SENDERS, file, file)
+		}
+	}
+	//snapshot before the funding, which is expected by the audit, since the
+	//funding transfers may still be pending once the senders hold enough
+	if AUDIT {
+		Auditor = bench.NewAuditor()
+		addrs := Workload.Destinations()
+		if Senders != nil {
+			for _, acc := range Senders.Accounts() {
+				addrs = append(addrs, acc.Address)
+			}
+		} else {
+			addrs = append(addrs, Admin.Address)
+		}
+		err = Auditor.Snapshot(OntSdk.Rpc, addrs)
+		if err != nil {
+			fmt.Printf("Audit snapshot error:%s\n", err)
+			return 1
+		}
+	}
+	if Senders != nil {
+		if FUND == 0 && COUNT <= 0 {
+			fmt.Println("-fund should be set for a run without -r")
+			return 1
+		}
+		var ont, ong uint64
+		if COUNT > 0 {
+			ont, ong = Workload.Funds(uint64((totalCount() + SENDERS - 1) / SENDERS))
+		}
+		if FUND > 0 {
+			ont = FUND
+		}
+		fmt.Printf("Funding %d senders with %d ont and %d ong:%v\n", Senders.Size(), ont, ong, time.Now())
+		err = Senders.Fund(OntSdk.Rpc, Admin, ont, ong, DEADLINE, Auditor)
+		if err != nil {
+			fmt.Printf("Fund senders error:%s\n", err)
+			sweepSenders()
+			return 1
+		}
 	}
-	TestTransfer()
+	stopCh := make(chan struct{})
+	//the confirmer and the analyzer share one block follower
+	listeners := make([]bench.BlockListener, 0)
+	if CONFIRM != "" {
+		Confirmer = bench.NewConfirmer(DEADLINE)
+		listeners = append(listeners, Confirmer)
+	}
+	if BLOCKS != "" {
+		if CONFIRM != "" && CONFIRM != BLOCKS {
+			fmt.Println("-blocks and -confirm should follow the blocks the same way")
+			return 1
+		}
+		Analyzer = bench.NewAnalyzer(GEN_BLOCK_TIME, config.Parameters.MaxTxInBlock)
+		listeners = append(listeners, Analyzer)
+	}
+	if len(listeners) > 0 {
+		mode := CONFIRM
+		if mode == "" {
+			mode = BLOCKS
+		}
+		switch mode {
+		case "poll":
+			err = bench.PollBlocks(OntSdk.Rpc, POLL, stopCh, listeners...)
+		case "ws":
+			err = bench.SubscribeBlocks(strings.Split(WS, ",")[0], stopCh, listeners...)
+		default:
+			err = fmt.Errorf("unknown block follow mode %s", mode)
+		}
+		if err != nil {
+			fmt.Printf("Follow blocks error:%s\n", err)
+			return 1
+		}
+	}
+	if MEMPOOL > 0 {
+		Mempool = bench.NewMempool(strings.Split(RPC, ",")[0], MEMPOOL_WINDOW*GEN_BLOCK_TIME)
+		go Mempool.Run(MEMPOOL, stopCh)
+	}
+	//the first signal stop the run, the second one abort the waiting for
+	//in-flight requests and confirmations
+	signalCtx, interrupt := context.WithCancel(context.Background())
+	abortCtx, abort := context.WithCancel(context.Background())
+	sc := make(chan os.Signal, 2)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sc
+		fmt.Printf("received %v, stop the run\n", sig)
+		interrupt()
+		sig = <-sc
+		fmt.Printf("received %v, abort waiting\n", sig)
+		abort()
+	}()
+	if Agent != nil {
+		go func() {
+			<-Agent.Stopped()
+			fmt.Println("stopped by the coordinator")
+			interrupt()
+		}()
+		fmt.Printf("Agent %d ready:%v\n", AgentPlan.Index, time.Now())
+		select {
+		case <-Agent.Ready():
+		case <-signalCtx.Done():
+		}
+	}
+	runCtx := signalCtx
+	if DURATION > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(signalCtx, WARMUP+DURATION)
+		defer cancel()
+	}
+	aborted := TestTransfer(runCtx, abortCtx)
+	if Confirmer != nil {
+		Confirmer.Wait(abortCtx)
+		Confirmer.Print()
+	}
+	if Analyzer != nil {
+		//follow the blocks until the submitted transactions are committed
+		waitCtx, cancel := context.WithTimeout(abortCtx, DEADLINE)
+		Analyzer.Wait(waitCtx, ANALYZE_IDLE*GEN_BLOCK_TIME)
+		cancel()
+		Analyzer.Print()
+	}
+	close(stopCh)
+	if Mempool != nil {
+		Mempool.Print()
+	}
+	if Auditor != nil {
+		if Confirmer != nil {
+			Auditor.Exclude(Confirmer.Missing())
+			Auditor.Exclude(Confirmer.WarmupMissing())
+		}
+		summary, err := Auditor.Audit(OntSdk.Rpc, DEADLINE)
+		if err != nil {
+			fmt.Printf("Audit error:%s\n", err)
+		} else {
+			bench.PrintAudit(summary)
+		}
+	}
+	sweepSenders()
+	report := writeReport(signalCtx.Err() != nil, aborted)
 	balance, err = OntSdk.Rpc.GetBalance(Admin.Address)
 	if err != nil {
 		fmt.Printf("GetBalance error:%s\n", err)
-		return
+	} else {
+		fmt.Printf("Admin ont left:%d\n", balance.Ont)
 	}
-	<-time.After(time.Second * 3)
-	fmt.Printf("Admin ont left:%d\n", balance.Ont)
-	if balance.Ont < 0 {
-		fmt.Printf("Admin balance not enought\n")
-		return
+	if Agent != nil {
+		//the coordinator check the merged report
+		return 0
 	}
+	return checkSLO(report)
 }
-func TestTransfer() {
-	taskCh := make(chan int, WORKER)
-	timerCh := make(chan int, 1)
-	toAcc, _ := common.AddressFromBase58(TO)
-	workerid := 0
-	index := 0
-	work := func() {
-		for {
-			select {
-			case t := <-taskCh:
-				if t == 0 {
-					workerid++
-					fmt.Printf("worker %d done:%v\n", workerid, time.Now())
-					if workerid == WORKER {
-						timerCh <- 0
-					}
-					return
-				}
-				index++
-				_, err := OntSdk.Rpc.Transfer(0, 30000+uint64(index), "ont", Admin, toAcc, 1)
-				if err != nil {
-					fmt.Printf("transfer error:%s\n", err)
-					return
-				}
+
+// newEndpoints return the EndpointPool submitting through TRANSPORT
+func newEndpoints() (*bench.EndpointPool, error) {
+	var dialErr error
+	switch TRANSPORT {
+	case bench.TRANSPORT_RPC:
+		return bench.NewEndpointPool(strings.Split(RPC, ","), LB, func(address string) bench.OpClient {
+			ontSdk := sdk.NewOntologySdk()
+			ontSdk.Rpc.SetAddress(address)
+			return ontSdk.Rpc
+		})
+	case bench.TRANSPORT_REST:
+		return bench.NewEndpointPool(strings.Split(REST, ","), LB, func(address string) bench.OpClient {
+			return bench.NewRestClient(address)
+		})
+	case bench.TRANSPORT_WS:
+		clients := make([]*bench.WsClient, 0)
+		pool, err := bench.NewEndpointPool(strings.Split(WS, ","), LB, func(address string) bench.OpClient {
+			if dialErr != nil {
+				return nil
+			}
+			client, err := bench.NewWsClient(address, WORKER)
+			if err != nil {
+				dialErr = err
+				return nil
+			}
+			clients = append(clients, client)
+			return client
+		})
+		if err == nil {
+			err = dialErr
+		}
+		if err != nil {
+			//close the connections dialed before the failure
+			for _, client := range clients {
+				client.Close()
 			}
+			return nil, err
+		}
+		return pool, nil
+	}
+	return nil, fmt.Errorf("unknown transport %s", TRANSPORT)
+}
+
+func loadSenders(file string, n int) (*bench.SenderPool, error) {
+	wallet, err := OntSdk.OpenWallet(file)
+	if err != nil {
+		return nil, fmt.Errorf("OpenWallet error:%s", err)
+	}
+	if wallet.GetAccountCount() < SENDER_OFF+n {
+		return nil, fmt.Errorf("wallet %s has only %d accounts", file, wallet.GetAccountCount())
+	}
+	accounts := make([]*account.Account, 0, n)
+	for i := 0; i < n; i++ {
+		acc, err := wallet.GetAccountByIndex(SENDER_OFF+i+1, []byte(WALLET_PWD))
+		if err != nil {
+			return nil, fmt.Errorf("GetAccountByIndex %d error:%s", SENDER_OFF+i+1, err)
+		}
+		accounts = append(accounts, acc)
+	}
+	return bench.NewSenderPool(accounts), nil
+}
+
+func sweepSenders() {
+	if Senders == nil {
+		return
+	}
+	//the balances are only final once the pending transfers are packed
+	err := Senders.Settle(OntSdk.Rpc, bench.SWEEP_BLOCKS, DEADLINE)
+	if err != nil {
+		fmt.Printf("Wait for the pending transfers of the senders error:%s\n", err)
+	}
+	fmt.Printf("Sweeping %d senders:%v\n", Senders.Size(), time.Now())
+	err = Senders.Sweep(OntSdk.Rpc, Admin)
+	if err != nil {
+		fmt.Printf("Sweep senders error:%s\n", err)
+	}
+}
+
+// writeReport write the report of the run, and hand it to the coordinator if
+// the process is an agent
+func writeReport(interrupted bool, aborted string) *bench.Report {
+	report := bench.NewReport("ont-bench", config.Version)
+	report.Interrupted = interrupted
+	report.Aborted = aborted
+	report.Transport = TRANSPORT
+	report.Model = MODEL
+	report.Concurrency = WORKER
+	flag.VisitAll(func(f *flag.Flag) {
+		report.Config[f.Name] = f.Value.String()
+	})
+	Stats.Summarize(report)
+	if Rate != nil {
+		report.SendLag = Rate.Lag().Summarize()
+	}
+	if Signer != nil {
+		report.Signing = Signer.Summarize()
+	}
+	Endpoints.Summarize(report)
+	if Confirmer != nil {
+		Confirmer.Summarize(report)
+	}
+	if Analyzer != nil {
+		Analyzer.Summarize(report)
+	}
+	if Mempool != nil {
+		Mempool.Summarize(report)
+	}
+	if Auditor != nil {
+		Auditor.Summarize(report)
+	}
+	if Agent != nil {
+		Agent.Finish(&bench.AgentResult{
+			Index:      AgentPlan.Index,
+			Report:     report,
+			Histograms: bench.Collect(Stats, Confirmer, Endpoints, Signer),
+		})
+	}
+	if REPORT != "" {
+		if err := report.WriteJSON(REPORT); err != nil {
+			fmt.Printf("Write report error:%s\n", err)
+		}
+	}
+	if CSV != "" {
+		if err := report.WriteCSV(CSV); err != nil {
+			fmt.Printf("Write csv error:%s\n", err)
 		}
 	}
+	return report
+}
+
+// runSLO return the SLO of the scenario overridden by the slo flags set
+func runSLO() (*bench.SLO, error) {
+	slo := &bench.SLO{}
+	if SCENARIO != "" {
+		scenario, err := bench.LoadScenario(SCENARIO)
+		if err != nil {
+			return nil, err
+		}
+		if scenario.SLO != nil {
+			*slo = *scenario.SLO
+		}
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "mintps":
+			slo.MinTPS = MIN_TPS
+		case "maxp99":
+			slo.MaxP99 = float64(MAX_P99) / float64(time.Millisecond)
+		case "maxerrors":
+			slo.MaxErrorRate = MAX_ERRORS
+		case "minconfirm":
+			slo.MinConfirmRatio = MIN_CONFIRM
+		}
+	})
+	return slo, nil
+}
+
+// checkSLO print the violations of the SLO by report, return the exit code
+// of the run
+func checkSLO(report *bench.Report) int {
+	slo, err := runSLO()
+	if err != nil {
+		fmt.Printf("LoadScenario error:%s\n", err)
+		return 1
+	}
+	if slo.Empty() {
+		return 0
+	}
+	violations := slo.Check(report)
+	if len(violations) == 0 {
+		fmt.Println("SLO passed")
+		return 0
+	}
+	for _, v := range violations {
+		fmt.Printf("SLO violated:%s\n", v)
+	}
+	return bench.EXIT_SLO_VIOLATED
+}
+
+// blocks without a committed transaction after which the analyzer stop
+// following the blocks of a finished run
+const ANALYZE_IDLE = 3
+
+// blocks spanned by a window of the mempool saturation check
+const MEMPOOL_WINDOW = 2
+
+// max wait of an agent for the coordinator to fetch its outcome
+const AGENT_RESULT_TIMEOUT = time.Minute
 
-	for i := 0; i < WORKER; i++ {
-		go work()
+// runAgent wait for the plan of the coordinator and run its share of the
+// bench
+func runAgent(args []string) int {
+	flags := flag.NewFlagSet("agent", flag.ExitOnError)
+	listen := flags.String("listen", ":7070", "Address to serve the coordinator")
+	flags.Usage = func() {
+		fmt.Println("Usage: ont-bench agent [options]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	Agent = bench.NewAgent()
+	err := Agent.Serve(*listen)
+	if err != nil {
+		fmt.Printf("Agent serve error:%s\n", err)
+		return 1
+	}
+	defer Agent.Close()
+	fmt.Printf("Agent waiting for the plan at %s\n", Agent.Address())
+	//a bad plan fail the agent instead of exiting before the coordinator see it
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	return bench.RunAgent(Agent, func(plan *bench.Plan) int {
+		AgentPlan = plan
+		fmt.Printf("Agent %d of %d, args:%s\n", plan.Index, plan.Agents, strings.Join(plan.Args, " "))
+		err := flag.CommandLine.Parse(plan.Args)
+		if err != nil {
+			return 2
+		}
+		adjustFlags()
+		return runBench()
+	}, AGENT_RESULT_TIMEOUT)
+}
+
+// agentArgs return the flags of the i-th of n agents, the rate, the request
+// count, the workers and the sender accounts are split over the agents
+func agentArgs(i, n int) []string {
+	args := make([]string, 0)
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "r", "w", "warmupcount", "senders", "senderoffset", "share", "report", "csv", "metrics":
+		default:
+			args = append(args, fmt.Sprintf("-%s=%s", f.Name, f.Value.String()))
+		}
+	})
+	//the first total%n agents take one more
+	share := func(total int) (int, int) {
+		size, rest := total/n, total%n
+		if i < rest {
+			return size + 1, i * (size + 1)
+		}
+		return size, rest*(size+1) + (i-rest)*size
+	}
+	if COUNT > 0 {
+		count, _ := share(COUNT)
+		args = append(args, fmt.Sprintf("-r=%d", count))
 	}
+	if WARMUP_CNT > 0 {
+		count, _ := share(WARMUP_CNT)
+		args = append(args, fmt.Sprintf("-warmupcount=%d", count))
+	}
+	workers, _ := share(WORKER)
+	if workers == 0 {
+		workers = 1
+	}
+	args = append(args, fmt.Sprintf("-w=%d", workers))
+	senders, offset := share(SENDERS)
+	args = append(args, fmt.Sprintf("-senders=%d", senders), fmt.Sprintf("-share=%v", 1/float64(n)))
+	if SENDER_FILE != "" {
+		args = append(args, fmt.Sprintf("-senderoffset=%d", SENDER_OFF+offset))
+	}
+	return args
+}
 
-	reqCount := 0
-	timer := time.NewTicker(time.Second)
-	for {
+// coordinate split the bench of the flags over the agents at the start
+// barrier, and merge their results into one report
+func coordinate(args []string) int {
+	flags := flag.NewFlagSet("coordinator", flag.ExitOnError)
+	agents := flags.String("agents", "", "Comma separated addresses of the agents")
+	flags.Usage = func() {
+		fmt.Println("Usage: ont-bench [bench options] coordinator -agents host:port,host:port")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if *agents == "" {
+		flags.Usage()
+		return 2
+	}
+	coordinator := bench.NewCoordinator(strings.Split(*agents, ","))
+	n := len(coordinator.Agents())
+	if REPLAY != "" || AUDIT {
+		fmt.Println("-replay and -audit can not be used with the coordinator")
+		return 2
+	}
+	if SENDERS < n {
+		fmt.Printf("-senders should be at least the agent num %d so every agent send from its own accounts\n", n)
+		return 2
+	}
+	plans := make([]*bench.Plan, 0, n)
+	for i := 0; i < n; i++ {
+		plans = append(plans, &bench.Plan{Index: i, Agents: n, Args: agentArgs(i, n)})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sc := make(chan os.Signal, 1)
+	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
 		select {
-		case <-timer.C:
-			fmt.Printf("Transfer start:%v\n", time.Now())
-			for i := 0; i < TPS; i++ {
-				taskCh <- 1
-				reqCount++
-				if reqCount == COUNT {
-					for i := 0; i < WORKER; i++ {
-						taskCh <- 0
-						timer.Stop()
-					}
-					break
-				}
-			}
-			fmt.Printf("transfer complete:%d\n", reqCount)
-		case t := <-timerCh:
-			if t == 0 {
-				return
+		case sig := <-sc:
+			fmt.Printf("received %v, stop the agents\n", sig)
+			if err := coordinator.Stop(); err != nil {
+				fmt.Printf("Stop agents error:%s\n", err)
 			}
+		case <-ctx.Done():
+		}
+	}()
+	err := coordinator.Assign(plans)
+	if err != nil {
+		fmt.Printf("Assign plans error:%s\n", err)
+		return 1
+	}
+	fmt.Printf("Waiting for %d agents to set up:%v\n", n, time.Now())
+	err = coordinator.Wait(ctx, bench.AGENT_READY)
+	if err != nil {
+		fmt.Printf("Wait agents error:%s\n", err)
+		coordinator.Stop()
+		return 1
+	}
+	err = coordinator.Start()
+	if err != nil {
+		fmt.Printf("Start agents error:%s\n", err)
+		coordinator.Stop()
+		return 1
+	}
+	fmt.Printf("Agents started:%v\n", time.Now())
+	err = coordinator.Wait(ctx, bench.AGENT_DONE)
+	if err != nil {
+		fmt.Printf("Wait agents error:%s\n", err)
+		return 1
+	}
+	results, err := coordinator.Results()
+	if err != nil {
+		fmt.Printf("Collect results error:%s\n", err)
+		return 1
+	}
+	report := bench.NewReport("ont-bench", config.Version)
+	flag.VisitAll(func(f *flag.Flag) {
+		report.Config[f.Name] = f.Value.String()
+	})
+	report.Config["agents"] = *agents
+	bench.MergeResults(report, results)
+	bench.PrintReport(report)
+	if REPORT != "" {
+		if err := report.WriteJSON(REPORT); err != nil {
+			fmt.Printf("Write report error:%s\n", err)
+		}
+	}
+	if CSV != "" {
+		if err := report.WriteCSV(CSV); err != nil {
+			fmt.Printf("Write csv error:%s\n", err)
+		}
+	}
+	return checkSLO(report)
+}
+
+// createWallet create the wallet file with n accounts of keyType, the first
+// one is the default account
+func createWallet(file string, n int, keyType string) ([]*account.Account, error) {
+	if _, err := os.Stat(file); err == nil {
+		return nil, fmt.Errorf("wallet %s already exists", file)
+	}
+	pkType, curve, scheme, err := bench.SenderKey(keyType)
+	if err != nil {
+		return nil, err
+	}
+	wallet, err := OntSdk.CreateWallet(file)
+	if err != nil {
+		return nil, fmt.Errorf("CreateWallet error:%s", err)
+	}
+	accounts := make([]*account.Account, 0, n)
+	for i := 0; i < n; i++ {
+		acc, err := wallet.CreateAccount(pkType, curve, scheme, []byte(WALLET_PWD))
+		if err != nil {
+			return nil, fmt.Errorf("CreateAccount error:%s", err)
+		}
+		accounts = append(accounts, acc)
+	}
+	err = wallet.Save()
+	if err != nil {
+		return nil, fmt.Errorf("save wallet error:%s", err)
+	}
+	return accounts, nil
+}
+
+// bootstrap create the admin wallet holding the bookkeepers and the sender
+// wallet, write the bookkeepers into the node config and print the funding
+// plan of the run
+func bootstrap(args []string) int {
+	flags := flag.NewFlagSet("bootstrap", flag.ExitOnError)
+	accounts := flags.Int("accounts", 100, "Number of sender accounts to create")
+	consensus := flags.String("consensus", bench.CONSENSUS_SOLO, "Consensus of the node config: solo or dbft")
+	bookkeepers := flags.Int("bookkeepers", config.DBFT_MIN_NODE_NUM, "Number of dbft bookkeepers")
+	cfgFile := flags.String("config", config.DEFAULT_CONFIG_FILE_NAME, "Node config file to write the bookkeepers into")
+	keeperKey := flags.String("bookkeeperkeytype", bench.KEY_TYPE_ECDSA, "Key type of the bookkeepers in the admin wallet, -keytype is the one of the senders")
+	count := flags.Int("r", COUNT, "Request count of the run to fund, -r of ont-bench by default")
+	flags.Usage = func() {
+		fmt.Println("Usage: ont-bench [-wallet admin.dat] [-senderwallet senders.dat] [-keytype ecdsa] bootstrap [options]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	switch *consensus {
+	case bench.CONSENSUS_SOLO:
+		*bookkeepers = config.SOLO_MIN_NODE_NUM
+	case bench.CONSENSUS_DBFT:
+		if *bookkeepers < config.DBFT_MIN_NODE_NUM {
+			fmt.Printf("dbft needs at least %d bookkeepers\n", config.DBFT_MIN_NODE_NUM)
+			return 2
 		}
+	default:
+		fmt.Printf("unknown consensus %s\n", *consensus)
+		return 2
+	}
+	if *accounts <= 0 {
+		fmt.Println("-accounts should be positive")
+		return 2
+	}
+	senderFile := SENDER_FILE
+	if senderFile == "" {
+		senderFile = "./senders.dat"
+	}
+	if senderFile == WALLET_FILE {
+		fmt.Println("-senderwallet should differ from -wallet")
+		return 2
+	}
+	OntSdk = sdk.NewOntologySdk()
+	if *count <= 0 && FUND == 0 {
+		fmt.Println("-r or -fund should be set to plan the funding")
+		return 2
+	}
+	keepers, err := createWallet(WALLET_FILE, *bookkeepers, *keeperKey)
+	if err != nil {
+		fmt.Printf("Create admin wallet error:%s\n", err)
+		return 1
+	}
+	senders, err := createWallet(senderFile, *accounts, KEY_TYPE)
+	if err != nil {
+		fmt.Printf("Create sender wallet error:%s\n", err)
+		return 1
+	}
+	pubkeys := make([]string, 0, len(keepers))
+	for _, acc := range keepers {
+		pubkeys = append(pubkeys, hex.EncodeToString(keypair.SerializePublicKey(acc.PublicKey)))
+	}
+	err = bench.WriteBookkeepers(*cfgFile, *consensus, pubkeys)
+	if err != nil {
+		fmt.Printf("Write bookkeepers error:%s\n", err)
+		return 1
+	}
+	fmt.Printf("Created %s with %d %s %s bookkeepers, the admin is the first one\n", WALLET_FILE, len(keepers), *keeperKey, *consensus)
+	for i, pubkey := range pubkeys {
+		fmt.Printf("  bookkeeper %d:%s %s\n", i+1, keepers[i].Address.ToBase58(), pubkey)
+	}
+	fmt.Printf("Created %s with %d %s senders\n", senderFile, len(senders), KEY_TYPE)
+	fmt.Printf("Wrote the bookkeepers into %s\n", *cfgFile)
+
+	total := *count
+	if total > 0 {
+		total += WARMUP_CNT
+	}
+	fund := FUND
+	if fund == 0 {
+		fund = uint64((total + len(senders) - 1) / len(senders))
+	}
+	fmt.Println("Funding plan:")
+	fmt.Printf("  admin %s needs %d ont, %d ont for each of %d senders\n",
+		keepers[0].Address.ToBase58(), fund*uint64(len(senders)), fund, len(senders))
+	fmt.Printf("  the senders are funded by the run and swept back afterwards, ong for the gas is not needed at gas price 0\n")
+	run := fmt.Sprintf("ont-bench -wallet %s -pwd <pwd> -senderwallet %s -senders %d -fund %d",
+		WALLET_FILE, senderFile, len(senders), fund)
+	if *count > 0 {
+		run += fmt.Sprintf(" -r %d", *count)
+	}
+	if *count > 0 && WARMUP_CNT > 0 {
+		run += fmt.Sprintf(" -warmupcount %d", WARMUP_CNT)
+	}
+	fmt.Printf("  run:%s\n", run)
+	return 0
+}
+
+// compareReports diff the report of a run against a baseline report, exit
+// non-zero if any metric regress beyond the tolerance
+func compareReports(args []string) int {
+	tol := &bench.Tolerance{}
+	flags := flag.NewFlagSet("compare", flag.ExitOnError)
+	flags.Float64Var(&tol.TPS, "tps", 5, "Max drop of tps in percent")
+	flags.Float64Var(&tol.Latency, "latency", 10, "Max growth of latency percentiles in percent")
+	flags.Float64Var(&tol.ErrorRate, "errors", 1, "Max growth of error rate in percentage points")
+	flags.Usage = func() {
+		fmt.Println("Usage: ont-bench compare [options] base.json current.json")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return 2
+	}
+	base, err := bench.LoadReport(flags.Arg(0))
+	if err != nil {
+		fmt.Printf("LoadReport error:%s\n", err)
+		return 2
+	}
+	current, err := bench.LoadReport(flags.Arg(1))
+	if err != nil {
+		fmt.Printf("LoadReport error:%s\n", err)
+		return 2
+	}
+	if base.Model != current.Model || base.Concurrency != current.Concurrency {
+		fmt.Printf("base ran %s model with concurrency %d, current %s model with concurrency %d\n",
+			base.Model, base.Concurrency, current.Model, current.Concurrency)
+	}
+	if base.Transport != current.Transport {
+		fmt.Printf("base submitted through %s, current through %s\n", base.Transport, current.Transport)
+	}
+	if bench.PrintDiffs(bench.Compare(base, current, tol)) > 0 {
+		return 1
+	}
+	return 0
+}
 
+// TestTransfer run the bench until it is over or ctx is done, then wait at
+// most GRACE for the in-flight requests unless abortCtx is done. It return
+// the reason if the bench aborted the run itself
+func TestTransfer(ctx, abortCtx context.Context) string {
+	if MODEL == bench.MODEL_CLOSED {
+		//the virtual users send as fast as the node respond, no target rate
+		Loop = bench.NewClosedLoop(totalCount(), THINK)
+		Stats = bench.NewStats(bench.ConstProfile(0))
+	} else {
+		Rate = bench.NewProfileController(Profile, totalCount())
+		Stats = bench.NewStats(Profile)
+	}
+	Stats.SetWarmup(uint64(WARMUP_CNT), WARMUP)
+	Nonces = bench.NewNonces()
+	if SIGNERS > 0 {
+		Signer = bench.NewSigner(OntSdk.Rpc, Nonces, SIGNERS, Stats.Warmup)
+	}
+	Runner = &bench.Runner{
+		Workload:  Workload,
+		Admin:     Admin,
+		Senders:   Senders,
+		Builder:   OntSdk.Rpc,
+		Nonces:    Nonces,
+		Endpoints: Endpoints,
+		Stats:     Stats,
+		Rate:      Rate,
+		Loop:      Loop,
+		Signer:    Signer,
+		Confirmer: Confirmer,
+		Analyzer:  Analyzer,
+		Mempool:   Mempool,
+		Auditor:   Auditor,
+		Workers:   WORKER,
+		Queue:     QUEUE,
+		SignBuf:   SIGN_BUF,
+		ErrBudget: ERR_BUDGET,
+	}
+
+	fmt.Printf("Transfer start through %s, %s model:%v\n", TRANSPORT, MODEL, time.Now())
+	doneCh := Runner.Run(ctx)
+	defer Runner.Stop()
+	select {
+	case <-doneCh:
+	case <-Runner.Stopped():
+		fmt.Printf("Transfer stopped:%v\n", time.Now())
+		select {
+		case <-doneCh:
+		case <-time.After(GRACE):
+			fmt.Printf("in-flight requests not done after %v\n", GRACE)
+		case <-abortCtx.Done():
+		}
+	}
+	Stats.Finish()
+	fmt.Printf("transfer complete:%v\n", time.Now())
+	if Rate != nil {
+		fmt.Println(Rate.Lag())
+	}
+	Stats.Print()
+	if Signer != nil {
+		Signer.Print()
 	}
+	Endpoints.Print()
+	return Runner.Aborted()
 }
@@ -3,7 +3,6 @@ package main
 import (
 	"bufio"
 	"bytes"
-	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"github.com/urfave/cli"
@@ -13,6 +12,8 @@ import (
 
 	"github.com/ontio/ontology-crypto/keypair"
 	//ldgactor "github.com/ontio/ontology-stress-test/actor"
+	sdk "github.com/ontio/ontology-go-sdk"
+	"github.com/ontio/ontology-stress-test/bench"
 	"github.com/ontio/ontology/account"
 	_ "github.com/ontio/ontology/cli"
 	test "github.com/ontio/ontology/cli/test"
@@ -80,7 +81,7 @@ func testAction(c *cli.Context) (err error) {
 	txnNum := c.Int("num")
 	passwd := c.String("password")
 	genFile := c.Bool("gen")
-	acct := account.Open("wallet.dat", []byte(passwd))
+	acct := account.Open(c.String("wallet"), []byte(passwd))
 	if acct == nil {
 		fmt.Println(" can not get default account")
 		os.Exit(1)
@@ -139,12 +140,14 @@ func GenTransferFile(n int, acc *account.Account, fileName string) {
 		f.Close()
 	}()
 
+	//build the transfers the way ont-bench does, the nonce make them unique
+	builder := sdk.NewOntologySdk().Rpc
+	op := bench.DefaultWorkload(acc.Address).Next()
+	nonces := bench.NewNonces()
 	for i := 0; i < n; i++ {
-		to := acc.Address
-		binary.BigEndian.PutUint64(to[:], uint64(i))
-		tx := test.NewOntTransferTransaction(acc.Address, to, 1)
-		if err := signTransaction(acc, tx); err != nil {
-			fmt.Println("signTransaction error:", err)
+		tx, err := op.Sign(builder, acc, nonces.Next(acc.Address))
+		if err != nil {
+			fmt.Println("sign transfer error:", err)
 			os.Exit(1)
 		}
 
@@ -190,6 +193,11 @@ func NewCommand() *cli.Command {
 				Usage: "sample transaction numbers",
 				Value: 1,
 			},
+			cli.StringFlag{
+				Name:  "wallet, w",
+				Usage: "wallet file, ont-bench bootstrap create one",
+				Value: "wallet.dat",
+			},
 			cli.StringFlag{
 				Name:  "password, p",
 				Usage: "wallet password",